@@ -0,0 +1,381 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package main
+
+import (
+	"cmp"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/dmabry/gochecks/internal/config"
+	"github.com/dmabry/gochecks/internal/plugin"
+	"github.com/dmabry/gochecks/internal/snmp"
+	"github.com/dmabry/gochecks/internal/threshold"
+	"github.com/dmabry/gomonitor"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The UCD-SNMP-MIB raw CPU counters, cumulative ticks since boot. ssCpuRawSystem already includes the
+// kernel, interrupt and soft interrupt time on Linux, so those counters are not added to the total.
+// ssCpuRawWait and ssCpuRawSteal are not implemented on every platform and count as 0 when missing.
+const (
+	oidSsCpuRawUser   = ".1.3.6.1.4.1.2021.11.50.0"
+	oidSsCpuRawNice   = ".1.3.6.1.4.1.2021.11.51.0"
+	oidSsCpuRawSystem = ".1.3.6.1.4.1.2021.11.52.0"
+	oidSsCpuRawIdle   = ".1.3.6.1.4.1.2021.11.53.0"
+	oidSsCpuRawWait   = ".1.3.6.1.4.1.2021.11.54.0"
+	oidSsCpuRawSteal  = ".1.3.6.1.4.1.2021.11.64.0"
+)
+
+// oidCpmCPUTotal5minRev is CISCO-PROCESS-MIB::cpmCPUTotal5minRev, the 5 minute CPU busy percentage of each CPU.
+// oidHrProcessorLoad is HOST-RESOURCES-MIB::hrProcessorLoad, the 1 minute average load of each processor in percent.
+const (
+	oidCpmCPUTotal5minRev = ".1.3.6.1.4.1.9.9.109.1.1.1.1.8"
+	oidHrProcessorLoad    = ".1.3.6.1.2.1.25.3.3.1.2"
+)
+
+// errNoTicks is returned by CalculateCPUUsage when the raw counters did not advance between the samples.
+var errNoTicks = errors.New("no CPU ticks elapsed between the samples, increase -delay")
+
+// CPUTicks holds a sample of the UCD-SNMP raw CPU counters.
+type CPUTicks struct {
+	User   uint64
+	Nice   uint64
+	System uint64
+	Idle   uint64
+	Wait   uint64
+	Steal  uint64
+}
+
+// CPUUsage holds the CPU time percentages between two CPUTicks samples.
+type CPUUsage struct {
+	User        float64
+	System      float64
+	Wait        float64
+	Idle        float64
+	Utilization float64
+}
+
+// GetCPUTicks retrieves the UCD-SNMP raw CPU counters from the SNMP target.
+// It returns an snmp.ErrNoSuchObject error if the target does not implement ssCpuRawIdle.
+func GetCPUTicks(snmpClient *snmp.Client) (*CPUTicks, error) {
+	oids := []string{oidSsCpuRawUser, oidSsCpuRawNice, oidSsCpuRawSystem, oidSsCpuRawIdle, oidSsCpuRawWait, oidSsCpuRawSteal}
+	result, _, err := snmpClient.GetValue(oids)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]uint64)
+	for _, variable := range result.Variables {
+		if value, err := snmp.ToFloat64(variable.Value); err == nil {
+			values[variable.Name] = uint64(value)
+		}
+	}
+	if _, ok := values[oidSsCpuRawIdle]; !ok {
+		return nil, &snmp.Error{Kind: snmp.ErrNoSuchObject, Err: fmt.Errorf("ssCpuRawIdle is not available, the target may not implement UCD-SNMP-MIB")}
+	}
+
+	return &CPUTicks{
+		User:   values[oidSsCpuRawUser],
+		Nice:   values[oidSsCpuRawNice],
+		System: values[oidSsCpuRawSystem],
+		Idle:   values[oidSsCpuRawIdle],
+		Wait:   values[oidSsCpuRawWait],
+		Steal:  values[oidSsCpuRawSteal],
+	}, nil
+}
+
+// tickDelta returns the increase of a Counter32 tick counter between two samples, assuming a counter that
+// went backwards wrapped once.
+func tickDelta(first uint64, second uint64) uint64 {
+	if second >= first {
+		return second - first
+	}
+	return (1<<32 - first) + second
+}
+
+// CalculateCPUUsage computes the CPU time percentages between two samples of the raw CPU counters.
+// The utilization is 100 minus the idle percentage. It returns an error if no ticks elapsed between the samples.
+func CalculateCPUUsage(first CPUTicks, second CPUTicks) (CPUUsage, error) {
+	user := tickDelta(first.User, second.User) + tickDelta(first.Nice, second.Nice)
+	system := tickDelta(first.System, second.System)
+	idle := tickDelta(first.Idle, second.Idle)
+	wait := tickDelta(first.Wait, second.Wait)
+	steal := tickDelta(first.Steal, second.Steal)
+	total := user + system + idle + wait + steal
+	if total == 0 {
+		return CPUUsage{}, errNoTicks
+	}
+
+	percent := func(ticks uint64) float64 {
+		return float64(ticks) * 100 / float64(total)
+	}
+	return CPUUsage{
+		User:        percent(user),
+		System:      percent(system),
+		Wait:        percent(wait),
+		Idle:        percent(idle),
+		Utilization: 100 - percent(idle),
+	}, nil
+}
+
+// GetUCDCPUUsage samples the UCD-SNMP raw CPU counters of the SNMP target twice, delay apart, and returns
+// the CPU time percentages between the samples, so the utilization reflects the current load rather than
+// the average since boot.
+func GetUCDCPUUsage(snmpClient *snmp.Client, delay time.Duration) (*CPUUsage, error) {
+	first, err := GetCPUTicks(snmpClient)
+	if err != nil {
+		return nil, err
+	}
+	time.Sleep(delay)
+	second, err := GetCPUTicks(snmpClient)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := CalculateCPUUsage(*first, *second)
+	if err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+// GetHostResourcesCPUUsage walks hrProcessorLoad on the SNMP target and returns the average load of all
+// processors, which most switches and servers without UCD-SNMP-MIB implement.
+// It returns an snmp.ErrNoSuchObject error if the target has no processor entries.
+func GetHostResourcesCPUUsage(snmpClient *snmp.Client) (float64, error) {
+	result, _, err := snmpClient.Walk(oidHrProcessorLoad)
+	if err != nil {
+		return 0, err
+	}
+
+	var total float64
+	for oid, value := range result {
+		load, err := snmp.ToFloat64(value)
+		if err != nil {
+			return 0, fmt.Errorf("value for OID %s is not numeric: %w", oid, err)
+		}
+		total += load
+	}
+	if len(result) == 0 {
+		return 0, &snmp.Error{Kind: snmp.ErrNoSuchObject, Err: fmt.Errorf("hrProcessorLoad is not available, the target may not implement HOST-RESOURCES-MIB")}
+	}
+	return total / float64(len(result)), nil
+}
+
+// GetCiscoCPUUsage walks cpmCPUTotal5minRev on the SNMP target and returns the 5 minute CPU utilization
+// of each CPU keyed by its cpmCPUTotalIndex. It returns an snmp.ErrNoSuchObject error if the target has
+// no CPU entries.
+func GetCiscoCPUUsage(snmpClient *snmp.Client) (map[string]float64, error) {
+	result, _, err := snmpClient.Walk(oidCpmCPUTotal5minRev)
+	if err != nil {
+		return nil, err
+	}
+
+	perCPU := make(map[string]float64, len(result))
+	for oid, value := range result {
+		index, err := snmp.TableIndex(oidCpmCPUTotal5minRev, oid)
+		if err != nil {
+			return nil, err
+		}
+		usage, err := snmp.ToFloat64(value)
+		if err != nil {
+			return nil, fmt.Errorf("value for OID %s is not numeric: %w", oid, err)
+		}
+		perCPU[index] = usage
+	}
+	if len(perCPU) == 0 {
+		return nil, &snmp.Error{Kind: snmp.ErrNoSuchObject, Err: fmt.Errorf("cpmCPUTotal5minRev is not available, the target may not implement CISCO-PROCESS-MIB")}
+	}
+	return perCPU, nil
+}
+
+// CPUMeasurement is the CPU utilization of an SNMP target as measured by GetCPUUsage.
+type CPUMeasurement struct {
+	// Utilization is the utilization in percent that is checked against the thresholds.
+	Utilization float64
+	// Usage holds the CPU time percentages, only set when measured from the UCD-SNMP raw ticks.
+	Usage *CPUUsage
+	// PerCPU holds the utilization of each CPU keyed by its index, only set for CISCO-PROCESS-MIB.
+	PerCPU map[string]float64
+}
+
+// cpuVendors are the MIBs tried, in order, by GetCPUUsage with the "auto" vendor.
+var cpuVendors = []string{"ucd", "hostresources", "cisco"}
+
+// GetCPUUsage measures the CPU utilization of the SNMP target in percent from the MIB selected by vendor:
+// "ucd" for the UCD-SNMP-MIB raw ticks sampled delay apart, "hostresources" for the average hrProcessorLoad,
+// "cisco" for the highest cpmCPUTotal5minRev of all CPUs, or "auto" to use the first of them the target
+// implements.
+func GetCPUUsage(snmpClient *snmp.Client, vendor string, delay time.Duration) (CPUMeasurement, error) {
+	switch vendor {
+	case "ucd":
+		usage, err := GetUCDCPUUsage(snmpClient, delay)
+		if err != nil {
+			return CPUMeasurement{}, err
+		}
+		return CPUMeasurement{Utilization: usage.Utilization, Usage: usage}, nil
+	case "hostresources":
+		utilization, err := GetHostResourcesCPUUsage(snmpClient)
+		return CPUMeasurement{Utilization: utilization}, err
+	case "cisco":
+		perCPU, err := GetCiscoCPUUsage(snmpClient)
+		if err != nil {
+			return CPUMeasurement{}, err
+		}
+		measurement := CPUMeasurement{PerCPU: perCPU}
+		for _, utilization := range perCPU {
+			measurement.Utilization = max(measurement.Utilization, utilization)
+		}
+		return measurement, nil
+	case "auto":
+		for _, source := range cpuVendors {
+			measurement, err := GetCPUUsage(snmpClient, source, delay)
+			if !errors.Is(err, snmp.ErrNoSuchObject) {
+				return measurement, err
+			}
+		}
+		return CPUMeasurement{}, fmt.Errorf("the target implements none of UCD-SNMP-MIB, HOST-RESOURCES-MIB or CISCO-PROCESS-MIB")
+	default:
+		return CPUMeasurement{}, fmt.Errorf("invalid vendor '%s', expected auto, ucd, hostresources or cisco", vendor)
+	}
+}
+
+// DetermineCPUUsage evaluates the CPU utilization in percent against the warn and crit ranges, where a nil
+// range disables that level.
+// The message lists the user, system and wait percentages when measurement.Usage is set, and the
+// utilization of each CPU when measurement.PerCPU is set.
+// If enablePerf is true, the utilization is added as performance data along with those values.
+//
+// Example:
+//
+//	warn, _ := threshold.Parse("80")
+//	crit, _ := threshold.Parse("95")
+//	measurement, _ := GetCPUUsage(&snmpClient, "cisco", 0)
+//	result := DetermineCPUUsage(measurement, &warn, &crit, true)
+//	result.SendResult()
+func DetermineCPUUsage(measurement CPUMeasurement, warn *threshold.Range, crit *threshold.Range, enablePerf bool) *gomonitor.CheckResult {
+	checkResult := gomonitor.NewCheckResult()
+	utilization, usage := measurement.Utilization, measurement.Usage
+	cpuIndexes := make([]string, 0, len(measurement.PerCPU))
+	for index := range measurement.PerCPU {
+		cpuIndexes = append(cpuIndexes, index)
+	}
+	slices.SortFunc(cpuIndexes, compareIndex)
+
+	message := fmt.Sprintf("CPU utilization %.2f%%", utilization)
+	if usage != nil {
+		message += fmt.Sprintf(" (user %.2f%%, system %.2f%%, wait %.2f%%)", usage.User, usage.System, usage.Wait)
+	}
+	if len(cpuIndexes) > 0 {
+		cpus := make([]string, 0, len(cpuIndexes))
+		for _, index := range cpuIndexes {
+			cpus = append(cpus, fmt.Sprintf("cpu %s %.2f%%", index, measurement.PerCPU[index]))
+		}
+		message += fmt.Sprintf(" (5 minute: %s)", strings.Join(cpus, ", "))
+	}
+
+	cpuPerf := gomonitor.PerformanceMetric{Value: utilization, Min: 0, Max: 100, UnitOM: "%"}
+	status := gomonitor.OK
+	if warn != nil {
+		cpuPerf.Warn = warn.Bound()
+		if warn.Breaches(utilization) {
+			status = gomonitor.Warning
+		}
+	}
+	if crit != nil {
+		cpuPerf.Crit = crit.Bound()
+		if crit.Breaches(utilization) {
+			status = gomonitor.Critical
+		}
+	}
+	checkResult.SetResult(status, message)
+
+	if enablePerf {
+		checkResult.AddPerformanceData("cpu", cpuPerf)
+		if usage != nil {
+			checkResult.AddPerformanceData("user", gomonitor.PerformanceMetric{Value: usage.User, Min: 0, Max: 100, UnitOM: "%"})
+			checkResult.AddPerformanceData("system", gomonitor.PerformanceMetric{Value: usage.System, Min: 0, Max: 100, UnitOM: "%"})
+			checkResult.AddPerformanceData("wait", gomonitor.PerformanceMetric{Value: usage.Wait, Min: 0, Max: 100, UnitOM: "%"})
+		}
+		for _, index := range cpuIndexes {
+			checkResult.AddPerformanceData("cpu_"+index, gomonitor.PerformanceMetric{Value: measurement.PerCPU[index], Min: 0, Max: 100, UnitOM: "%"})
+		}
+	}
+	return checkResult
+}
+
+// compareIndex orders numeric table indexes by value, falling back to string order for other indexes.
+func compareIndex(a string, b string) int {
+	aNumber, aErr := strconv.Atoi(a)
+	bNumber, bErr := strconv.Atoi(b)
+	if aErr == nil && bErr == nil {
+		return cmp.Compare(aNumber, bNumber)
+	}
+	return strings.Compare(a, b)
+}
+
+// main is the entry point of the program. It parses command-line flags, creates an SNMP client,
+// and measures the CPU utilization of the target SNMP device with GetCPUUsage. By default the first MIB
+// the target implements is used, trying the UCD-SNMP raw CPU counters, sampled -delay seconds apart,
+// then hrProcessorLoad and finally cpmCPUTotal5minRev.
+// The result of the check is then sent using the SendResult method.
+func main() {
+	snmpFlags := snmp.Flags{}
+	snmpFlags.Register()
+	vendor := flag.String("vendor", "auto", "The MIB to read the CPU utilization from: auto, ucd (UCD-SNMP-MIB raw ticks), hostresources (HOST-RESOURCES-MIB hrProcessorLoad) or cisco (CISCO-PROCESS-MIB).")
+	delay := flag.Int("delay", 5, "The delay in seconds to wait between the raw tick samples.")
+	warn := flag.String("warn", "", "Warning range for the CPU utilization in percent, e.g. 80. If not provided, there is no warning level.")
+	crit := flag.String("crit", "", "Critical range for the CPU utilization in percent, e.g. 95. If not provided, there is no critical level.")
+	enablePerfData := flag.Bool("enablePerfData", false, "Enable performance data. Default is false.")
+	if err := config.Parse(); err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+
+	if *vendor != "auto" && !slices.Contains(cpuVendors, *vendor) {
+		plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid vendor '%s', expected auto, ucd, hostresources or cisco", *vendor))
+	}
+	warnRange, err := threshold.ParseOptional(*warn)
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid warn: %s", err))
+	}
+	critRange, err := threshold.ParseOptional(*crit)
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid crit: %s", err))
+	}
+
+	snmpClient, err := snmpFlags.Client()
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+	if snmpFlags.Validate {
+		plugin.Validate(&snmpClient)
+	}
+
+	measurement, err := GetCPUUsage(&snmpClient, *vendor, time.Duration(*delay)*time.Second)
+	if errors.Is(err, errNoTicks) {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+	if err != nil {
+		eMessage := fmt.Sprintf("SNMP target %s failed to return CPU utilization. %s", snmpClient.Target, err)
+		plugin.Exit(plugin.ErrorStatus(err), eMessage)
+	}
+	result := DetermineCPUUsage(measurement, warnRange, critRange, *enablePerfData)
+	result.SendResult()
+}
@@ -0,0 +1,143 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/dmabry/gochecks/internal/config"
+	"github.com/dmabry/gochecks/internal/plugin"
+	"github.com/dmabry/gochecks/internal/snmp"
+	"github.com/dmabry/gochecks/internal/storage"
+	"github.com/dmabry/gochecks/internal/threshold"
+	"github.com/dmabry/gomonitor"
+	"regexp"
+	"strings"
+)
+
+// DetermineDiskUsage evaluates the used space of every fixed disk in units against the warn and crit
+// ranges in percent, where a nil range disables that level. Only units of type hrStorageFixedDisk whose
+// description (the mount point) matches mount are checked; a nil mount checks every fixed disk.
+// Units reporting a size of 0, as some virtual filesystems do, are skipped.
+// If enablePerf is true, the used bytes of each mount are added as performance data with the size as maximum.
+//
+// Example:
+//
+//	units, _ := storage.Collect(&snmpClient)
+//	warn, _ := threshold.Parse("80")
+//	crit, _ := threshold.Parse("90")
+//	result := DetermineDiskUsage(units, regexp.MustCompile("^/var"), &warn, &crit, true)
+//	result.SendResult()
+func DetermineDiskUsage(units []storage.Unit, mount *regexp.Regexp, warn *threshold.Range, crit *threshold.Range, enablePerf bool) *gomonitor.CheckResult {
+	checkResult := gomonitor.NewCheckResult()
+
+	status := gomonitor.OK
+	var problems []string
+	checked := 0
+	for _, unit := range units {
+		if unit.Type != storage.OIDHrStorageFixedDisk {
+			continue
+		}
+		if mount != nil && !mount.MatchString(unit.Description) {
+			continue
+		}
+		percent, ok := unit.UsedPercent()
+		if !ok {
+			continue
+		}
+		checked++
+
+		perfData := gomonitor.PerformanceMetric{Value: float64(unit.UsedBytes()), Min: 0, Max: float64(unit.SizeBytes()), UnitOM: "B"}
+		description := fmt.Sprintf("%s %.2f%%", unit.Description, percent)
+		if warn != nil {
+			perfData.Warn = warn.Bound() * float64(unit.SizeBytes()) / 100
+		}
+		if crit != nil {
+			perfData.Crit = crit.Bound() * float64(unit.SizeBytes()) / 100
+		}
+		if crit != nil && crit.Breaches(percent) {
+			status = gomonitor.Critical
+			problems = append(problems, description)
+		} else if warn != nil && warn.Breaches(percent) {
+			if status == gomonitor.OK {
+				status = gomonitor.Warning
+			}
+			problems = append(problems, description)
+		}
+		if enablePerf {
+			checkResult.AddPerformanceData(unit.Description, perfData)
+		}
+	}
+
+	if checked == 0 {
+		checkResult.SetResult(gomonitor.Unknown, "No fixed disks matched")
+		return checkResult
+	}
+	message := fmt.Sprintf("%d disk(s) checked", checked)
+	if len(problems) > 0 {
+		message = "Disk usage exceeds threshold: " + strings.Join(problems, ", ")
+	}
+	checkResult.SetResult(status, message)
+	return checkResult
+}
+
+// main is the entry point of the program. It parses command-line flags, creates an SNMP client,
+// walks the hrStorageTable of the target SNMP device and evaluates it using the DetermineDiskUsage function.
+// The result of the check is then sent using the SendResult method.
+func main() {
+	snmpFlags := snmp.Flags{}
+	snmpFlags.Register()
+	mount := flag.String("mount", "", "Regex the mount point must match for the disk to be checked, e.g. ^/var. If not provided, all fixed disks are checked.")
+	warnPct := flag.String("warnPct", "", "Warning range for the used space in percent, e.g. 80. If not provided, there is no warning level.")
+	critPct := flag.String("critPct", "", "Critical range for the used space in percent, e.g. 90. If not provided, there is no critical level.")
+	enablePerfData := flag.Bool("enablePerfData", false, "Enable performance data. Default is false.")
+	if err := config.Parse(); err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+
+	warnRange, err := threshold.ParseOptional(*warnPct)
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid warnPct: %s", err))
+	}
+	critRange, err := threshold.ParseOptional(*critPct)
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid critPct: %s", err))
+	}
+	var pattern *regexp.Regexp
+	if *mount != "" {
+		pattern, err = regexp.Compile(*mount)
+		if err != nil {
+			plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid mount '%s': %s", *mount, err))
+		}
+	}
+
+	snmpClient, err := snmpFlags.Client()
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+	if snmpFlags.Validate {
+		plugin.Validate(&snmpClient)
+	}
+	units, err := storage.Collect(&snmpClient)
+	if err != nil {
+		eMessage := fmt.Sprintf("SNMP target %s failed to return hrStorageTable. %s", snmpClient.Target, err)
+		plugin.Exit(plugin.ErrorStatus(err), eMessage)
+	}
+
+	result := DetermineDiskUsage(units, pattern, warnRange, critRange, *enablePerfData)
+	result.SendResult()
+}
@@ -0,0 +1,242 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/dmabry/gochecks/internal/config"
+	"github.com/dmabry/gochecks/internal/plugin"
+	"github.com/dmabry/gochecks/internal/snmp"
+	"github.com/dmabry/gomonitor"
+	"sort"
+	"strings"
+)
+
+// The CISCO-ENVMON-MIB fan and power supply status entries, and the ENTITY-MIB and ENTITY-STATE-MIB columns
+// used for other vendors.
+const (
+	oidCiscoEnvMonFanStatusEntry    = ".1.3.6.1.4.1.9.9.13.1.4.1"
+	oidCiscoEnvMonSupplyStatusEntry = ".1.3.6.1.4.1.9.9.13.1.5.1"
+	oidEntPhysicalClass             = ".1.3.6.1.2.1.47.1.1.1.1.5"
+	oidEntPhysicalName              = ".1.3.6.1.2.1.47.1.1.1.1.7"
+	oidEntStateOper                 = ".1.3.6.1.2.1.131.1.1.1.3"
+)
+
+// The CISCO-ENVMON-MIB CiscoEnvMonState values, the entPhysicalClass values of fans and power supplies,
+// the entStateOper value of a disabled entity, and the columns of the Cisco status entries.
+const (
+	ciscoEnvMonStateNormal      = 1
+	ciscoEnvMonStateNotPresent  = 5
+	entPhysicalClassPowerSupply = 6
+	entPhysicalClassFan         = 7
+	entStateOperDisabled        = 2
+	ciscoEnvMonColumnDescr      = 2
+	ciscoEnvMonColumnState      = 3
+)
+
+// ciscoEnvMonStateNames maps CiscoEnvMonState values to their names.
+var ciscoEnvMonStateNames = map[int]string{
+	1: "normal",
+	2: "warning",
+	3: "critical",
+	4: "shutdown",
+	5: "notPresent",
+	6: "notFunctioning",
+}
+
+// Component is a fan or power supply and whether it is healthy.
+type Component struct {
+	Kind    string
+	Name    string
+	State   string
+	Healthy bool
+}
+
+// getCiscoComponents walks a CISCO-ENVMON-MIB status table and returns its entries as components of kind.
+// Entries that are notPresent, such as empty power supply slots, are skipped.
+func getCiscoComponents(session *snmp.Session, entryOid string, kind string) ([]Component, error) {
+	table, _, err := session.GetTable(entryOid)
+	if err != nil {
+		return nil, err
+	}
+
+	var components []Component
+	for index, row := range table {
+		state, err := snmp.ToFloat64(row[ciscoEnvMonColumnState])
+		if err != nil || state == ciscoEnvMonStateNotPresent {
+			continue
+		}
+		name := snmp.ToString(row[ciscoEnvMonColumnDescr])
+		if _, ok := row[ciscoEnvMonColumnDescr]; !ok {
+			name = kind + " " + index
+		}
+		stateName, ok := ciscoEnvMonStateNames[int(state)]
+		if !ok {
+			stateName = fmt.Sprintf("unknown(%d)", int(state))
+		}
+		components = append(components, Component{Kind: kind, Name: name, State: stateName, Healthy: state == ciscoEnvMonStateNormal})
+	}
+	return components, nil
+}
+
+// GetCiscoComponents returns the fans and power supplies of the SNMP target from CISCO-ENVMON-MIB.
+func GetCiscoComponents(snmpClient *snmp.Client) ([]Component, error) {
+	return snmp.WithSession(snmpClient, func(session *snmp.Session) ([]Component, error) {
+		fans, err := getCiscoComponents(session, oidCiscoEnvMonFanStatusEntry, "fan")
+		if err != nil {
+			return nil, err
+		}
+		supplies, err := getCiscoComponents(session, oidCiscoEnvMonSupplyStatusEntry, "psu")
+		if err != nil {
+			return nil, err
+		}
+		return append(fans, supplies...), nil
+	})
+}
+
+// GetEntityComponents returns the fans and power supplies of the SNMP target from ENTITY-MIB, with
+// their operational state from ENTITY-STATE-MIB. A component is unhealthy when entStateOper is disabled.
+func GetEntityComponents(snmpClient *snmp.Client) ([]Component, error) {
+	return snmp.WithSession(snmpClient, getEntityComponents)
+}
+
+// getEntityComponents walks the ENTITY-MIB and ENTITY-STATE-MIB columns over session for GetEntityComponents.
+func getEntityComponents(session *snmp.Session) ([]Component, error) {
+	classes, _, err := session.Walk(oidEntPhysicalClass)
+	if err != nil {
+		return nil, err
+	}
+	names, _, err := session.Walk(oidEntPhysicalName)
+	if err != nil {
+		return nil, err
+	}
+	states, _, err := session.Walk(oidEntStateOper)
+	if err != nil {
+		return nil, err
+	}
+
+	var components []Component
+	for oid, value := range classes {
+		class, err := snmp.ToFloat64(value)
+		if err != nil {
+			continue
+		}
+		var kind string
+		switch class {
+		case entPhysicalClassFan:
+			kind = "fan"
+		case entPhysicalClassPowerSupply:
+			kind = "psu"
+		default:
+			continue
+		}
+		index, err := snmp.TableIndex(oidEntPhysicalClass, oid)
+		if err != nil {
+			return nil, err
+		}
+		name := snmp.ToString(names[oidEntPhysicalName+"."+index])
+		if name == "" {
+			name = kind + " " + index
+		}
+		state, err := snmp.ToFloat64(states[oidEntStateOper+"."+index])
+		if err != nil {
+			// Entities without ENTITY-STATE-MIB support have no state to evaluate
+			continue
+		}
+		components = append(components, Component{Kind: kind, Name: name, State: fmt.Sprintf("entStateOper %d", int(state)), Healthy: state != entStateOperDisabled})
+	}
+	return components, nil
+}
+
+// DetermineEnvironment returns Critical listing every unhealthy component of the given kind ("fan", "psu"
+// or "all"), otherwise OK. It returns Unknown when there are no components of that kind.
+//
+// Example:
+//
+//	components, _ := GetCiscoComponents(&snmpClient)
+//	result := DetermineEnvironment(components, "all")
+//	result.SendResult()
+func DetermineEnvironment(components []Component, kind string) *gomonitor.CheckResult {
+	checkResult := gomonitor.NewCheckResult()
+
+	checked := 0
+	var failed []string
+	for _, component := range components {
+		if kind != "all" && component.Kind != kind {
+			continue
+		}
+		checked++
+		if !component.Healthy {
+			failed = append(failed, fmt.Sprintf("%s %s is %s", component.Kind, component.Name, component.State))
+		}
+	}
+	sort.Strings(failed)
+
+	if checked == 0 {
+		checkResult.SetResult(gomonitor.Unknown, fmt.Sprintf("No %s components found", kind))
+		return checkResult
+	}
+	if len(failed) > 0 {
+		checkResult.SetResult(gomonitor.Critical, "Failed components: "+strings.Join(failed, ", "))
+		return checkResult
+	}
+	checkResult.SetResult(gomonitor.OK, fmt.Sprintf("%d component(s) normal", checked))
+	return checkResult
+}
+
+// main is the entry point of the program. It parses command-line flags, creates an SNMP client,
+// reads the fans and power supplies of the target SNMP device and evaluates them using the
+// DetermineEnvironment function. The result of the check is then sent using the SendResult method.
+func main() {
+	snmpFlags := snmp.Flags{}
+	snmpFlags.Register()
+	vendor := flag.String("vendor", "cisco", "The MIB to read fan and power supply states from: cisco (CISCO-ENVMON-MIB) or entity (ENTITY-STATE-MIB).")
+	component := flag.String("component", "all", "The components to check: fan, psu or all.")
+	if err := config.Parse(); err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+
+	if *component != "fan" && *component != "psu" && *component != "all" {
+		plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid component '%s', expected fan, psu or all", *component))
+	}
+
+	snmpClient, err := snmpFlags.Client()
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+	if snmpFlags.Validate {
+		plugin.Validate(&snmpClient)
+	}
+
+	var components []Component
+	switch *vendor {
+	case "cisco":
+		components, err = GetCiscoComponents(&snmpClient)
+	case "entity":
+		components, err = GetEntityComponents(&snmpClient)
+	default:
+		plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid vendor '%s', expected cisco or entity", *vendor))
+	}
+	if err != nil {
+		eMessage := fmt.Sprintf("SNMP target %s failed to return fan and power supply states. %s", snmpClient.Target, err)
+		plugin.Exit(plugin.ErrorStatus(err), eMessage)
+	}
+
+	result := DetermineEnvironment(components, *component)
+	result.SendResult()
+}
@@ -0,0 +1,155 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/dmabry/gochecks/internal/config"
+	"github.com/dmabry/gochecks/internal/interfaces"
+	"github.com/dmabry/gochecks/internal/plugin"
+	"github.com/dmabry/gochecks/internal/snmp"
+	"github.com/dmabry/gochecks/internal/threshold"
+	"github.com/dmabry/gomonitor"
+	"strconv"
+	"strings"
+)
+
+// ParseTypes parses a comma-separated list of ifType values, given as numbers or IANAifType names,
+// e.g. "6,ieee8023adLag", into a set of ifType numbers. An empty list returns a nil set, which matches
+// every interface.
+func ParseTypes(list string) (map[int]bool, error) {
+	if list == "" {
+		return nil, nil
+	}
+	types := make(map[int]bool)
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if ifType, err := strconv.Atoi(field); err == nil {
+			types[ifType] = true
+			continue
+		}
+		ifType, ok := interfaces.IfTypeByName(field)
+		if !ok {
+			return nil, fmt.Errorf("unknown ifType '%s'", field)
+		}
+		types[ifType] = true
+	}
+	return types, nil
+}
+
+// CountInterfaces walks ifType on the SNMP target and returns the number of interfaces, counting only
+// those whose ifType is in types unless types is nil.
+func CountInterfaces(snmpClient *snmp.Client, types map[int]bool) (int, error) {
+	result, _, err := snmpClient.Walk(interfaces.OIDIfType)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, value := range result {
+		ifType, ok := value.(int)
+		if !ok {
+			continue
+		}
+		if types == nil || types[ifType] {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// DetermineInterfaceCount evaluates the interface count against the warn and crit ranges, where a nil range
+// disables that level, and against expected, which is Critical when the count differs from it and is
+// disabled when negative. The count is always added as performance data.
+//
+// Example:
+//
+//	crit, _ := threshold.Parse("48:")
+//	result := DetermineInterfaceCount(count, 52, nil, &crit)
+//	result.SendResult()
+func DetermineInterfaceCount(count int, expected int, warn *threshold.Range, crit *threshold.Range) *gomonitor.CheckResult {
+	checkResult := gomonitor.NewCheckResult()
+
+	countPerf := gomonitor.PerformanceMetric{Value: float64(count), Min: 0}
+	status := gomonitor.OK
+	message := fmt.Sprintf("%d interfaces", count)
+	if warn != nil {
+		countPerf.Warn = warn.Bound()
+		if warn.Breaches(float64(count)) {
+			status = gomonitor.Warning
+		}
+	}
+	if crit != nil {
+		countPerf.Crit = crit.Bound()
+		if crit.Breaches(float64(count)) {
+			status = gomonitor.Critical
+		}
+	}
+	if expected >= 0 && count != expected {
+		status = gomonitor.Critical
+		message += fmt.Sprintf(", expected %d", expected)
+	}
+
+	checkResult.SetResult(status, message)
+	checkResult.AddPerformanceData("interfaces", countPerf)
+	return checkResult
+}
+
+// main is the entry point of the program. It parses command-line flags, creates an SNMP client,
+// and counts the interfaces of the target SNMP device with a single walk of ifType using the
+// CountInterfaces function. The result of the check is then sent using the SendResult method.
+func main() {
+	snmpFlags := snmp.Flags{}
+	snmpFlags.Register()
+	typeList := flag.String("type", "", "Only count interfaces of these ifTypes, as a comma-separated list of numbers or IANAifType names, e.g. ethernetCsmacd. If not provided, every interface is counted.")
+	expected := flag.Int("expected", -1, "Alert Critical when the count differs from this number. Default is -1 (disabled).")
+	warn := flag.String("warn", "", "Warning range for the interface count, e.g. 48:52. If not provided, there is no warning level.")
+	crit := flag.String("crit", "", "Critical range for the interface count, e.g. 48:. If not provided, there is no critical level.")
+	if err := config.Parse(); err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+
+	types, err := ParseTypes(*typeList)
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid type: %s", err))
+	}
+	warnRange, err := threshold.ParseOptional(*warn)
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid warn: %s", err))
+	}
+	critRange, err := threshold.ParseOptional(*crit)
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid crit: %s", err))
+	}
+
+	snmpClient, err := snmpFlags.Client()
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+	if snmpFlags.Validate {
+		plugin.Validate(&snmpClient)
+	}
+
+	count, err := CountInterfaces(&snmpClient, types)
+	if err != nil {
+		eMessage := fmt.Sprintf("SNMP target %s failed to return data when walking ifType. %s", snmpClient.Target, err)
+		plugin.Exit(plugin.ErrorStatus(err), eMessage)
+	}
+	result := DetermineInterfaceCount(count, *expected, warnRange, critRange)
+	result.SendResult()
+}
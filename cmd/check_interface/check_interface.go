@@ -0,0 +1,317 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/dmabry/gochecks/internal/config"
+	"github.com/dmabry/gochecks/internal/interfaces"
+	"github.com/dmabry/gochecks/internal/plugin"
+	"github.com/dmabry/gochecks/internal/snmp"
+	"github.com/dmabry/gochecks/internal/threshold"
+	"github.com/dmabry/gomonitor"
+	"strings"
+	"time"
+)
+
+// InterfaceHealth holds the status and the error and discard counters of a single interface.
+// The counters are the 32-bit IF-MIB Counter32 columns.
+type InterfaceHealth struct {
+	Name        string
+	AdminStatus int
+	OperStatus  int
+	InErrors    uint64
+	OutErrors   uint64
+	InDiscards  uint64
+	OutDiscards uint64
+}
+
+// Sample is a single measurement of an interface. Metrics is only collected when the usage is checked.
+type Sample struct {
+	Health  *InterfaceHealth
+	Metrics *interfaces.InterfaceMetrics
+}
+
+// Options selects the sub-checks EvaluateInterface runs and their thresholds. A nil range disables that level.
+type Options struct {
+	// CheckStatus flags an interface that is administratively up but operationally down as Critical.
+	CheckStatus bool
+	// CheckErrors evaluates the increase of InErrors+OutErrors and InDiscards+OutDiscards between the samples.
+	CheckErrors  bool
+	WarnErrors   *threshold.Range
+	CritErrors   *threshold.Range
+	WarnDiscards *threshold.Range
+	CritDiscards *threshold.Range
+	// CheckUsage evaluates the inbound and outbound utilization in percent of the link speed with
+	// interfaces.DetermineInterfaceUsage.
+	CheckUsage bool
+	WarnUsage  *threshold.Range
+	CritUsage  *threshold.Range
+	// MinInterval is the minimum time between the samples for a utilization to be reported.
+	MinInterval time.Duration
+	// EnablePerf adds the performance data of every enabled sub-check to the result.
+	EnablePerf bool
+}
+
+// counterValue returns the counter or gauge value of column as a uint64, treating a missing or
+// non-numeric value as 0.
+func counterValue(values map[string]interface{}, column string) uint64 {
+	value, err := snmp.ToFloat64(values[column])
+	if err != nil || value < 0 {
+		return 0
+	}
+	return uint64(value)
+}
+
+// GetInterfaceHealth retrieves the name, admin and oper status and the error and discard counters of the
+// interface with the given index in a single GET.
+//
+// Parameters:
+//   - snmpClient: The SNMP client used to retrieve the values.
+//   - index: The index of the interface.
+//
+// Returns:
+//   - health: The status and counters of the interface.
+//   - error: An error when the request fails or the interface does not exist.
+func GetInterfaceHealth(snmpClient *snmp.Client, index int) (*InterfaceHealth, error) {
+	oids := interfaces.InstanceOIDs(index, interfaces.OIDIfName, interfaces.OIDIfAdminStatus, interfaces.OIDIfOperStatus,
+		interfaces.OIDIfInErrors, interfaces.OIDIfOutErrors, interfaces.OIDIfInDiscards, interfaces.OIDIfOutDiscards)
+
+	result, _, err := snmpClient.GetValue(oids.List())
+	if err != nil {
+		return nil, err
+	}
+
+	values := oids.Values(result.Variables)
+	if _, ok := values[interfaces.OIDIfOperStatus]; !ok {
+		return nil, fmt.Errorf("interface with index %d does not exist", index)
+	}
+	name := snmp.ToString(values[interfaces.OIDIfName])
+	if name == "" {
+		name = fmt.Sprintf("index %d", index)
+	}
+
+	return &InterfaceHealth{
+		Name:        name,
+		AdminStatus: int(counterValue(values, interfaces.OIDIfAdminStatus)),
+		OperStatus:  int(counterValue(values, interfaces.OIDIfOperStatus)),
+		InErrors:    counterValue(values, interfaces.OIDIfInErrors),
+		OutErrors:   counterValue(values, interfaces.OIDIfOutErrors),
+		InDiscards:  counterValue(values, interfaces.OIDIfInDiscards),
+		OutDiscards: counterValue(values, interfaces.OIDIfOutDiscards),
+	}, nil
+}
+
+// GetSample measures the interface with the given index, collecting only what the enabled sub-checks need.
+func GetSample(snmpClient *snmp.Client, index int, options Options) (*Sample, error) {
+	var sample Sample
+	var err error
+	if options.CheckStatus || options.CheckErrors {
+		if sample.Health, err = GetInterfaceHealth(snmpClient, index); err != nil {
+			return nil, err
+		}
+	}
+	if options.CheckUsage {
+		if sample.Metrics, err = interfaces.GetInterfaceMetrics(snmpClient, index); err != nil {
+			return nil, err
+		}
+	}
+	return &sample, nil
+}
+
+// counter32Delta returns the increase of a Counter32 between two samples, accounting for a single wrap.
+func counter32Delta(first uint64, second uint64) uint64 {
+	return uint64(uint32(second) - uint32(first))
+}
+
+// evaluateRanges returns the status of value against the warn and crit ranges, where a nil range is disabled.
+func evaluateRanges(value float64, warn *threshold.Range, crit *threshold.Range) gomonitor.ExitCode {
+	switch {
+	case crit != nil && crit.Breaches(value):
+		return gomonitor.Critical
+	case warn != nil && warn.Breaches(value):
+		return gomonitor.Warning
+	default:
+		return gomonitor.OK
+	}
+}
+
+// boundOf returns the performance data level of r, or 0 when r is nil.
+func boundOf(r *threshold.Range) float64 {
+	if r == nil {
+		return 0
+	}
+	return r.Bound()
+}
+
+// EvaluateInterface runs the sub-checks enabled in options against two samples of an interface and merges
+// them into a single result with the worst status of all of them, the message of each sub-check and the
+// performance data of all of them. The status is taken from the second sample, while the errors, discards
+// and utilization are the changes between the samples.
+//
+// Parameters:
+//   - first: The earlier sample of the interface.
+//   - second: The later sample of the interface.
+//   - options: The sub-checks to run and their thresholds.
+//
+// Returns:
+//   - checkResult: The merged result of the enabled sub-checks.
+//
+// Example:
+//
+//	crit, _ := threshold.Parse("90")
+//	options := Options{CheckStatus: true, CheckUsage: true, CritUsage: &crit, EnablePerf: true}
+//	result := EvaluateInterface(*first, *second, options)
+//	result.SendResult()
+func EvaluateInterface(first Sample, second Sample, options Options) *gomonitor.CheckResult {
+	checkResult := gomonitor.NewCheckResult()
+	status := gomonitor.OK
+	var parts []string
+
+	if options.CheckStatus {
+		health := second.Health
+		detail := interfaces.InterfaceDetail{AdminStatus: health.AdminStatus, OperStatus: health.OperStatus}
+		statusResult := gomonitor.OK
+		if detail.IsAdminUpOperDown() {
+			statusResult = gomonitor.Critical
+		}
+		status = plugin.WorseStatus(status, statusResult)
+		parts = append(parts, fmt.Sprintf("%s admin %s oper %s", health.Name, interfaces.IfStatusName(health.AdminStatus), interfaces.IfStatusName(health.OperStatus)))
+		if options.EnablePerf {
+			checkResult.AddPerformanceData("oper_status", gomonitor.PerformanceMetric{Value: float64(health.OperStatus)})
+		}
+	}
+
+	if options.CheckErrors {
+		errors := counter32Delta(first.Health.InErrors, second.Health.InErrors) + counter32Delta(first.Health.OutErrors, second.Health.OutErrors)
+		discards := counter32Delta(first.Health.InDiscards, second.Health.InDiscards) + counter32Delta(first.Health.OutDiscards, second.Health.OutDiscards)
+		status = plugin.WorseStatus(status, evaluateRanges(float64(errors), options.WarnErrors, options.CritErrors))
+		status = plugin.WorseStatus(status, evaluateRanges(float64(discards), options.WarnDiscards, options.CritDiscards))
+		parts = append(parts, fmt.Sprintf("errors: %d discards: %d", errors, discards))
+		if options.EnablePerf {
+			checkResult.AddPerformanceData("errors", gomonitor.PerformanceMetric{Value: float64(errors), Warn: boundOf(options.WarnErrors), Crit: boundOf(options.CritErrors), Min: 0})
+			checkResult.AddPerformanceData("discards", gomonitor.PerformanceMetric{Value: float64(discards), Warn: boundOf(options.WarnDiscards), Crit: boundOf(options.CritDiscards), Min: 0})
+		}
+	}
+
+	if options.CheckUsage {
+		usage := interfaces.DetermineInterfaceUsage(*first.Metrics, *second.Metrics, options.WarnUsage, options.WarnUsage,
+			options.CritUsage, options.CritUsage, true, options.EnablePerf, options.MinInterval)
+		status = plugin.WorseStatus(status, usage.ExitCode)
+		parts = append(parts, usage.Message)
+		for _, name := range usage.PerfOrder {
+			checkResult.AddPerformanceData(name, usage.PerformanceData[name])
+		}
+	}
+
+	checkResult.SetResult(status, strings.Join(parts, "; "))
+	return checkResult
+}
+
+// main is the entry point of the program. It parses command-line flags, creates an SNMP client,
+// and takes two samples of the interface -delay seconds apart, evaluating its status, errors and
+// utilization in a single pass using the EvaluateInterface function.
+// The result of the check is then sent using the SendResult method.
+func main() {
+	snmpFlags := snmp.Flags{}
+	snmpFlags.Register()
+	index := flag.Int("index", 1, "The index of the Interface")
+	name := flag.String("name", "", "The ifName of the interface, resolved to its current index instead of using -index, e.g. Gi0/1.")
+	alias := flag.String("alias", "", "The ifAlias of the interface, resolved to its current index instead of using -index.")
+	delay := flag.Int("delay", 10, "The delay in seconds to wait between the samples")
+	checkStatus := flag.Bool("checkStatus", true, "Alert Critical when the interface is administratively up but operationally down. Default is true.")
+	checkErrors := flag.Bool("checkErrors", true, "Evaluate the errors and discards counted between the samples. Default is true.")
+	checkUsage := flag.Bool("checkUsage", true, "Evaluate the utilization in percent of the link speed. Default is true.")
+	warnErrors := flag.String("warnErrors", "", "Warning range for the InErrors+OutErrors counted between the samples, e.g. 10. If not provided, there is no warning level.")
+	critErrors := flag.String("critErrors", "", "Critical range for the InErrors+OutErrors counted between the samples, e.g. 100. If not provided, there is no critical level.")
+	warnDiscards := flag.String("warnDiscards", "", "Warning range for the InDiscards+OutDiscards counted between the samples. If not provided, there is no warning level.")
+	critDiscards := flag.String("critDiscards", "", "Critical range for the InDiscards+OutDiscards counted between the samples. If not provided, there is no critical level.")
+	warnUsage := flag.String("warnUsage", "", "Warning range for the inbound and outbound utilization in percent, e.g. 80. If not provided, there is no warning level.")
+	critUsage := flag.String("critUsage", "", "Critical range for the inbound and outbound utilization in percent, e.g. 95. If not provided, there is no critical level.")
+	minInterval := flag.Int("minInterval", 0, "The minimum interval in seconds between the samples for a utilization to be reported. Default is 0.")
+	enablePerfData := flag.Bool("enablePerfData", false, "Enable performance data. Default is false.")
+	resolveName := flag.Bool("resolveName", false, "Prefix the result message with the sysName of the target. Default is false.")
+	if err := config.Parse(); err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+
+	if !*checkStatus && !*checkErrors && !*checkUsage {
+		plugin.Exit(gomonitor.Unknown, "Nothing to check, enable at least one of -checkStatus, -checkErrors and -checkUsage")
+	}
+	if *name != "" && *alias != "" {
+		plugin.Exit(gomonitor.Unknown, "Only one of -name and -alias can be given")
+	}
+	parseRange := func(flagName string, spec string) *threshold.Range {
+		r, err := threshold.ParseOptional(spec)
+		if err != nil {
+			plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid %s: %s", flagName, err))
+		}
+		return r
+	}
+	options := Options{
+		CheckStatus:  *checkStatus,
+		CheckErrors:  *checkErrors,
+		WarnErrors:   parseRange("warnErrors", *warnErrors),
+		CritErrors:   parseRange("critErrors", *critErrors),
+		WarnDiscards: parseRange("warnDiscards", *warnDiscards),
+		CritDiscards: parseRange("critDiscards", *critDiscards),
+		CheckUsage:   *checkUsage,
+		WarnUsage:    parseRange("warnUsage", *warnUsage),
+		CritUsage:    parseRange("critUsage", *critUsage),
+		MinInterval:  time.Duration(*minInterval) * time.Second,
+		EnablePerf:   *enablePerfData,
+	}
+
+	snmpClient, err := snmpFlags.Client()
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+	if snmpFlags.Validate {
+		plugin.Validate(&snmpClient)
+	}
+
+	for column, value := range map[string]string{interfaces.OIDIfName: *name, interfaces.OIDIfAlias: *alias} {
+		if value == "" {
+			continue
+		}
+		resolved, err := interfaces.FindInterfaceIndex(&snmpClient, column, value)
+		if err != nil {
+			plugin.Exit(gomonitor.Unknown, fmt.Sprintf("SNMP target %s could not resolve interface: %s", snmpClient.Target, err))
+		}
+		*index = resolved
+	}
+
+	// plugin.Exit does not return, but return explicitly so a nil sample can never be dereferenced
+	first, err := GetSample(&snmpClient, *index, options)
+	if err != nil {
+		plugin.Exit(plugin.ErrorStatus(err), fmt.Sprintf("SNMP target %s failed to return data when sampling the interface. %s", snmpClient.Target, err))
+		return
+	}
+	time.Sleep(time.Duration(*delay) * time.Second)
+	second, err := GetSample(&snmpClient, *index, options)
+	if err != nil {
+		plugin.Exit(plugin.ErrorStatus(err), fmt.Sprintf("SNMP target %s failed to return data when sampling the interface. %s", snmpClient.Target, err))
+		return
+	}
+
+	result := EvaluateInterface(*first, *second, options)
+	if *resolveName {
+		plugin.PrefixSysName(&snmpClient, result)
+	}
+	result.SendResult()
+}
@@ -19,203 +19,412 @@ package main
 import (
 	"flag"
 	"fmt"
+	"github.com/dmabry/gochecks/internal/config"
+	"github.com/dmabry/gochecks/internal/graphite"
 	"github.com/dmabry/gochecks/internal/interfaces"
+	"github.com/dmabry/gochecks/internal/plugin"
 	"github.com/dmabry/gochecks/internal/snmp"
+	"github.com/dmabry/gochecks/internal/state"
+	"github.com/dmabry/gochecks/internal/threshold"
 	"github.com/dmabry/gomonitor"
+	"log/slog"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
-// InterfaceMetrics represents the metrics of a network interface.
-type InterfaceMetrics struct {
-	Name      string
-	In        uint
-	Out       uint
-	HCIn      uint64
-	HCOut     uint64
-	Speed     uint
-	HighSpeed uint
-	Latency   time.Duration
-	Timestamp time.Time
-}
-
-// convertToScale converts a given value to the appropriate scale (bps, Kbps, Mbps, or Gbps).
-// The function takes an input value in bits per second (bps) and returns the converted value
-// along with the corresponding unit of measurement.
+// FindInterfacesByAlias walks ifAlias on the target and returns the indexes of all interfaces
+// whose alias matches the provided pattern. The value of the first capture group (or the whole
+// match when the pattern has no groups) is compared against group, and only interfaces with an
+// equal value are returned. An empty group selects every interface matching the pattern.
 //
 // Parameters:
-//   - value: The input value in bits per second (bps) to be converted.
+//   - snmpClient: The SNMP client used to walk the ifAlias table.
+//   - pattern: The regular expression applied to each ifAlias, e.g. "^WAN:([^:]+):".
+//   - group: The capture group value to select, e.g. "ISP-A".
 //
 // Returns:
-//   - out: The converted value in the appropriate scale (bps, Kbps, Mbps, or Gbps).
-//   - unit: The corresponding unit of measurement for the converted value.
-func convertToScale(value uint64) (out uint64, unit string) {
-	bps := value * 8
-	if bps < 1000 {
-		return bps, "bps"
-	}
-
-	kbps := bps / 1000 // convert octets to Kbps
-	if kbps < 1000 {
-		return kbps, "Kbps"
+//   - indexes: The sorted interface indexes belonging to the group.
+//   - error: Any error encountered while walking the ifAlias table.
+func FindInterfacesByAlias(snmpClient *snmp.Client, pattern *regexp.Regexp, group string) ([]int, error) {
+	result, _, err := snmpClient.Walk(interfaces.OIDIfAlias)
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk ifAlias: %w", err)
 	}
 
-	mbps := kbps / 1000 // convert Kbps to Mbps
-	if mbps < 1000 {
-		return mbps, "Mbps"
+	var indexes []int
+	for oid, value := range result {
+		alias, ok := value.([]byte)
+		if !ok {
+			continue
+		}
+		matches := pattern.FindStringSubmatch(string(alias))
+		if matches == nil {
+			continue
+		}
+		captured := matches[0]
+		if len(matches) > 1 {
+			captured = matches[1]
+		}
+		if group != "" && captured != group {
+			continue
+		}
+		rawIndex, err := snmp.TableIndex(interfaces.OIDIfAlias, oid)
+		if err != nil {
+			return nil, err
+		}
+		index, err := strconv.Atoi(rawIndex)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert interface index to int: %w", err)
+		}
+		indexes = append(indexes, index)
 	}
+	sort.Ints(indexes)
 
-	gbps := mbps / 1000 // convert Mbps to Gbps
-	return gbps, "Gbps"
+	return indexes, nil
 }
 
-// GetInterfaceMetrics retrieves the network interface metrics for a specific interface
-// using the provided SNMP client and index.
+// GetAggregateInterfaceMetrics retrieves the metrics for each of the given interface indexes and
+// combines them into a single InterfaceMetrics named after the group, so the combined throughput of
+// several ports can be evaluated by DetermineInterfaceUsage like a single interface.
+// The counters of each interface are kept in Members, so their deltas are computed per interface and then
+// summed, while the link speeds are summed. Whether the 64-bit counters are used is decided per interface as well,
+// so the aggregate is only LowCapacity when none of its interfaces implements them.
+// The latency is the total time spent collecting all members and the timestamp is that of the
+// first member sampled.
 //
 // Parameters:
 //   - snmpClient: The SNMP client used to connect and retrieve the metrics.
-//   - index: The index of the interface to retrieve the metrics for.
+//   - name: The name given to the aggregated metrics.
+//   - indexes: The interface indexes to aggregate.
 //
 // Returns:
-//   - metrics: The network interface metrics for the specified interface.
+//   - metrics: The summed network interface metrics.
 //   - error: Any error encountered during the retrieval of the metrics.
-func GetInterfaceMetrics(snmpClient *snmp.Client, index int) (*InterfaceMetrics, error) {
-	strIndex := strconv.Itoa(index)
-	oidName := fmt.Sprintf("%s.%s", interfaces.OIDIfName, strIndex)
-	oidHCIn := fmt.Sprintf("%s.%s", interfaces.OIDIfHCInOctets, strIndex)
-	oidHCOut := fmt.Sprintf("%s.%s", interfaces.OIDIfHCOutOctets, strIndex)
-	oidIn := fmt.Sprintf("%s.%s", interfaces.OIDIfInOctets, strIndex)
-	oidOut := fmt.Sprintf("%s.%s", interfaces.OIDIfOutOctets, strIndex)
-	oidSpeed := fmt.Sprintf("%s.%s", interfaces.OIDIfSpeed, strIndex)
-	oidHighSpeed := fmt.Sprintf("%s.%s", interfaces.OIDIfHighSpeed, strIndex)
-	usageOIDs := []string{oidName, oidIn, oidOut, oidHCIn, oidHCOut, oidSpeed, oidHighSpeed}
-
-	result, latency, err := snmpClient.GetValue(usageOIDs)
-	if err != nil {
-		eMessage := fmt.Sprintf("Requested OID: %s", err)
-		return nil, fmt.Errorf("%s: %w", eMessage, err)
+func GetAggregateInterfaceMetrics(snmpClient *snmp.Client, name string, indexes []int) (*interfaces.InterfaceMetrics, error) {
+	if len(indexes) == 0 {
+		return nil, fmt.Errorf("no interfaces to aggregate for %s", name)
 	}
 
-	if result.Variables[0].Value == nil {
-		eMessage := fmt.Sprintf("Index doesn't exist?")
-		return nil, fmt.Errorf("%s", eMessage)
+	aggregate := &interfaces.InterfaceMetrics{Name: name, LowCapacity: true}
+	for i, index := range indexes {
+		metrics, err := interfaces.GetInterfaceMetrics(snmpClient, index)
+		if err != nil {
+			return nil, fmt.Errorf("interface index %d: %w", index, err)
+		}
+		if i == 0 {
+			aggregate.Timestamp = metrics.Timestamp
+		}
+		aggregate.Members = append(aggregate.Members, *metrics)
+		aggregate.Speed += metrics.Speed
+		aggregate.HighSpeed += metrics.HighSpeed
+		aggregate.LowCapacity = aggregate.LowCapacity && metrics.LowCapacity
+		aggregate.Latency += metrics.Latency
+	}
+
+	return aggregate, nil
+}
+
+// SwapSavedMetrics returns the metrics saved by the previous run under key in stateDir and saves
+// current in their place, so consecutive runs can compute a rate without sleeping between measurements.
+// It returns nil metrics on the first run, when no previous sample exists yet.
+//
+// Parameters:
+//   - stateDir: The directory holding the state files.
+//   - key: The key identifying the measured interface, e.g. the target and index.
+//   - current: The metrics measured by this run.
+//
+// Returns:
+//   - previous: The metrics measured by the previous run, or nil if there is none.
+//   - error: Any error encountered while reading or writing the state file.
+func SwapSavedMetrics(stateDir string, key string, current *interfaces.InterfaceMetrics) (*interfaces.InterfaceMetrics, error) {
+	previous := &interfaces.InterfaceMetrics{}
+	found, err := state.Load(stateDir, key, previous)
+	if err != nil {
+		return nil, err
 	}
+	if err := state.Save(stateDir, key, current); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, nil
+	}
+	return previous, nil
+}
 
-	metrics := &InterfaceMetrics{
-		Name:      string(result.Variables[0].Value.([]uint8)),
-		In:        result.Variables[1].Value.(uint),
-		Out:       result.Variables[2].Value.(uint),
-		HCIn:      result.Variables[3].Value.(uint64),
-		HCOut:     result.Variables[4].Value.(uint64),
-		Speed:     result.Variables[5].Value.(uint),
-		HighSpeed: result.Variables[6].Value.(uint),
-		Latency:   latency,
-		Timestamp: time.Now(),
+// sampleTarget is a single interface, or aggregate of interfaces, measured by a run, identified by the key
+// of its state file.
+type sampleTarget struct {
+	key string
+	get func() (*interfaces.InterfaceMetrics, error)
+}
+
+// sampleAll measures every target once, in order, returning an error for the first target that fails.
+func sampleAll(targets []sampleTarget) ([]*interfaces.InterfaceMetrics, error) {
+	samples := make([]*interfaces.InterfaceMetrics, 0, len(targets))
+	for _, target := range targets {
+		metrics, err := target.get()
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, metrics)
 	}
+	return samples, nil
+}
 
-	return metrics, nil
+// ParseIndexes parses a comma-separated list of interface indexes, e.g. "1,2,5", rejecting duplicates.
+func ParseIndexes(list string) ([]int, error) {
+	var indexes []int
+	seen := make(map[int]bool)
+	for _, field := range strings.Split(list, ",") {
+		index, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not an interface index", field)
+		}
+		if seen[index] {
+			return nil, fmt.Errorf("index %d is listed more than once", index)
+		}
+		seen[index] = true
+		indexes = append(indexes, index)
+	}
+	return indexes, nil
 }
 
-// DetermineInterfaceUsage calculates the usage of a network interface based on the provided InterfaceMetrics.
-// It compares the metrics between two time periods and determines if the inbound and outbound traffic exceeds
-// the given warning and critical thresholds. It also converts the traffic values to the appropriate scale (bps,
-// Kbps, Mbps, or Gbps) and crafts a message with the results.
+// CombineResults merges the results of several interfaces checked in one run into a single result with the
+// worst status of all of them. The messages are joined, with the ones not OK first, and the performance data
+// of each interface is prefixed with its name, e.g. "Gi0/1_in", so the metrics of the interfaces stay apart.
 //
 // Parameters:
-//   - first: The InterfaceMetrics representing the metrics of the first time period.
-//   - second: The InterfaceMetrics representing the metrics of the second time period.
-//   - warnIn: The warning threshold for inbound traffic in bps.
-//   - warnOut: The warning threshold for outbound traffic in bps.
-//   - critIn: The critical threshold for inbound traffic in bps.
-//   - critOut: The critical threshold for outbound traffic in bps.
-//   - enablePerf: A boolean indicating whether to include performance data in the check result.
+//   - names: The name of the interface of each result.
+//   - results: The result of each interface, in the same order as names.
 //
 // Returns:
-//   - checkResult: A pointer to a gomonitor.CheckResult object that represents the result of the interface usage calculation.
+//   - checkResult: The combined result.
 //
 // Example:
 //
-//	first := InterfaceMetrics{Name: "eth0", In: 100, Out: 200, HCIn: 300, HCOut: 400, Speed: 1000, Latency: 10 * time.Millisecond, Timestamp: time.Now()}
-//	second := InterfaceMetrics{Name: "eth0", In: 200, Out: 300, HCIn: 400, HCOut: 500, Speed: 1000, Latency: 20 * time.Millisecond, Timestamp: time.Now()}
-//	result := DetermineInterfaceUsage(first, second, 500, 500, 1000, 1000, true)
+//	result := CombineResults([]string{"Gi0/1", "Gi0/2"}, []*gomonitor.CheckResult{result1, result2})
 //	result.SendResult()
-func DetermineInterfaceUsage(first InterfaceMetrics, second InterfaceMetrics, warnIn int, warnOut int, critIn int, critOut int, enablePerf bool) *gomonitor.CheckResult {
+func CombineResults(names []string, results []*gomonitor.CheckResult) *gomonitor.CheckResult {
 	checkResult := gomonitor.NewCheckResult()
-	intName := first.Name
-	periodDiff := second.Timestamp.Sub(first.Timestamp)
-	period := periodDiff.Seconds()
-	avgLatency := (first.Latency + second.Latency) / 2
-	// Calc rates
-	in := (second.In - first.In) / uint(period)
-	out := (second.Out - first.Out) / uint(period)
-	hcIn := (second.HCIn - first.HCIn) / uint64(period)
-	hcOut := (second.HCOut - first.HCOut) / uint64(period)
-	// Convert to scale
-	intIn, intInUnit := convertToScale(uint64(in))
-	intOut, intOutUnit := convertToScale(uint64(out))
-	intHCIn, intHCInUnit := convertToScale(hcIn)
-	intHCOut, intHCOutUnit := convertToScale(hcOut)
-	// Craft message
-	message := fmt.Sprintf("%s - In: %d %s Out: %d %s HCIn: %d %s HCOut: %d %s", intName, intIn, intInUnit, intOut, intOutUnit, intHCIn, intHCInUnit, intHCOut, intHCOutUnit)
-	if enablePerf {
-		checkResult.AddPerformanceData("snmp_latency", gomonitor.PerformanceMetric{Value: avgLatency.Seconds(), UnitOM: "s"})
-		checkResult.AddPerformanceData("in", gomonitor.PerformanceMetric{Value: float64(in * 8), Warn: float64(warnIn), Crit: float64(critIn), Min: 0, Max: float64(first.Speed), UnitOM: "bps"})
-		checkResult.AddPerformanceData("out", gomonitor.PerformanceMetric{Value: float64(out * 8), Warn: float64(warnOut), Crit: float64(critOut), Min: 0, Max: float64(first.Speed), UnitOM: "bps"})
-		checkResult.AddPerformanceData("hc_in", gomonitor.PerformanceMetric{Value: float64(hcIn * 8), Warn: float64(warnIn), Crit: float64(critIn), Min: 0, Max: float64(first.Speed), UnitOM: "bps"})
-		checkResult.AddPerformanceData("hc_out", gomonitor.PerformanceMetric{Value: float64(hcOut * 8), Warn: float64(warnOut), Crit: float64(critOut), Min: 0, Max: float64(first.Speed), UnitOM: "bps"})
-	}
-
-	if intIn > uint64(critIn) || intHCIn > uint64(critIn) {
-		checkResult.SetResult(gomonitor.Critical, "Inbound exceeds threshold "+message)
-	} else if intIn > uint64(warnIn) || intHCIn > uint64(warnIn) {
-		checkResult.SetResult(gomonitor.Warning, "Inbound exceeds threshold "+message)
-	} else if intOut > uint64(critOut) || intHCOut > uint64(critOut) {
-		checkResult.SetResult(gomonitor.Critical, "Outbound exceeds threshold "+message)
-	} else if intOut > uint64(warnOut) || intHCOut > uint64(warnOut) {
-		checkResult.SetResult(gomonitor.Warning, "Outbound exceeds threshold "+message)
-	} else {
-		checkResult.SetResult(gomonitor.OK, message)
+	status := gomonitor.OK
+	var problems, messages []string
+	for i, result := range results {
+		status = plugin.WorseStatus(status, result.ExitCode)
+		if result.ExitCode == gomonitor.OK {
+			messages = append(messages, result.Message)
+		} else {
+			problems = append(problems, result.Message)
+		}
+		for _, metric := range result.PerfOrder {
+			checkResult.AddPerformanceData(names[i]+"_"+metric, result.PerformanceData[metric])
+		}
 	}
+	checkResult.SetResult(status, strings.Join(append(problems, messages...), ", "))
 	return checkResult
 }
 
 func main() {
-	target := flag.String("target", "127.0.0.1", "The target SNMP device.")
-	community := flag.String("community", "public", "The SNMP community string.")
+	snmpFlags := snmp.Flags{}
+	snmpFlags.Register()
 	index := flag.Int("index", 1, "The index of the Interface")
+	indexList := flag.String("indexes", "", "A comma-separated list of interface indexes to check in one run instead of -index, e.g. 1,2,5. All are sampled around a single -delay.")
 	delay := flag.Int("delay", 10, "The delay in seconds to wait between measurements")
+	stateDir := flag.String("stateDir", "", "Directory to save counters in between runs. When set, the rate is computed against the previous run instead of waiting -delay seconds.")
 	enablePerfData := flag.Bool("enablePerfData", false, "Enable performance data. Default is false.")
-	warnIn := flag.Int("warnIn", 0, "Warning level for inbound in bps. Default is 0.")
-	critIn := flag.Int("critIn", 0, "Critical level for inbound in bps. Default is 0.")
-	warnOut := flag.Int("warnOut", 0, "Warning level for outbound in bps. Default is 0.")
-	critOut := flag.Int("critOut", 0, "Critical level for outbound bps. Default is 0.")
-	flag.Parse()
+	warnIn := flag.String("warnIn", "", "Warning range for inbound in bps, e.g. 800000000 or @0:1000. If not provided, there is no warning level.")
+	critIn := flag.String("critIn", "", "Critical range for inbound in bps, e.g. 900000000 or @0:100. If not provided, there is no critical level.")
+	warnOut := flag.String("warnOut", "", "Warning range for outbound in bps, e.g. 800000000 or @0:1000. If not provided, there is no warning level.")
+	critOut := flag.String("critOut", "", "Critical range for outbound in bps, e.g. 900000000 or @0:100. If not provided, there is no critical level.")
+	percent := flag.Bool("percent", false, "Treat the warn and crit levels as percentages of the interface link speed. Default is false.")
+	minInterval := flag.Int("minInterval", 0, "The minimum interval in seconds between measurements for a rate to be reported, matching the agent's counter update granularity. Default is 0.")
+	name := flag.String("name", "", "The ifName of the interface, resolved to its current index instead of using -index, e.g. Gi0/1.")
+	alias := flag.String("alias", "", "The ifAlias of the interface, resolved to its current index instead of using -index.")
+	aliasPattern := flag.String("aliasPattern", "", "Regex applied to ifAlias to aggregate interfaces by its first capture group. If not provided, -index is used.")
+	nameCacheTTL := flag.Duration("nameCacheTTL", 0, "Cache the ifIndex to ifName, ifAlias and ifType map of the target in -stateDir for this long when resolving -name or -alias, e.g. 1h. Default is 0 (no cache).")
+	aliasGroup := flag.String("aliasGroup", "", "The capture group value of -aliasPattern to aggregate, e.g. ISP-A. If not provided, all matching interfaces are aggregated.")
+	resolveName := flag.Bool("resolveName", false, "Prefix the result message with the sysName of the target. Default is false.")
+	output := flag.String("output", "nagios", "The output of the rates, nagios or graphite. graphite also sends the rates to -carbonHost in addition to the check result.")
+	carbonHost := flag.String("carbonHost", "", "The Carbon plaintext listener as host:port, e.g. graphite:2003. Required with -output graphite.")
+	graphitePrefix := flag.String("graphitePrefix", "gochecks", "The prefix of the Graphite metric paths.")
+	if err := config.Parse(); err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
 
-	snmpClient := snmp.Client{
-		Target:    *target,
-		Community: *community,
+	snmpClient, err := snmpFlags.Client()
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+	if snmpFlags.Validate {
+		plugin.Validate(&snmpClient)
+	}
+
+	switch *output {
+	case "nagios":
+	case "graphite":
+		if *carbonHost == "" {
+			plugin.Exit(gomonitor.Unknown, "-carbonHost is required with -output graphite")
+		}
+	default:
+		plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid output '%s', must be nagios or graphite", *output))
+	}
+
+	thresholds := make(map[string]*threshold.Range)
+	for name, spec := range map[string]string{"warnIn": *warnIn, "warnOut": *warnOut, "critIn": *critIn, "critOut": *critOut} {
+		r, err := threshold.ParseOptional(spec)
+		if err != nil {
+			plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid %s: %s", name, err))
+		}
+		thresholds[name] = r
 	}
 
-	measure1, err1 := GetInterfaceMetrics(&snmpClient, *index)
-	if err1 != nil {
-		checkResult := gomonitor.NewCheckResult()
-		eMessage := fmt.Sprintf("SNMP target %s failed to return data when measuring metrics. %s", snmpClient.Target, err1)
-		checkResult.SetResult(gomonitor.Critical, eMessage)
-		checkResult.SendResult()
+	if *name != "" && *alias != "" {
+		plugin.Exit(gomonitor.Unknown, "Only one of -name and -alias can be given")
+	}
+	if *indexList != "" && (*name != "" || *alias != "" || *aliasPattern != "") {
+		plugin.Exit(gomonitor.Unknown, "-indexes cannot be combined with -name, -alias or -aliasPattern")
+	}
+	if *nameCacheTTL > 0 && *stateDir == "" {
+		plugin.Exit(gomonitor.Unknown, "-nameCacheTTL requires -stateDir")
+	}
+	for column, value := range map[string]string{interfaces.OIDIfName: *name, interfaces.OIDIfAlias: *alias} {
+		if value == "" {
+			continue
+		}
+		var resolved int
+		if *nameCacheTTL > 0 {
+			resolved, err = interfaces.FindCachedInterfaceIndex(&snmpClient, column, value, *stateDir, *nameCacheTTL)
+		} else {
+			resolved, err = interfaces.FindInterfaceIndex(&snmpClient, column, value)
+		}
+		if err != nil {
+			plugin.Exit(gomonitor.Unknown, fmt.Sprintf("SNMP target %s could not resolve interface: %s", snmpClient.Target, err))
+		}
+		*index = resolved
 	}
 
-	// delay
-	time.Sleep(time.Duration(*delay) * time.Second)
+	targets := []sampleTarget{{
+		key: fmt.Sprintf("check_interface_usage_%s_%d", snmpClient.Target, *index),
+		get: func() (*interfaces.InterfaceMetrics, error) {
+			return interfaces.GetInterfaceMetrics(&snmpClient, *index)
+		},
+	}}
 
-	measure2, err2 := GetInterfaceMetrics(&snmpClient, *index)
-	if err2 != nil {
-		checkResult := gomonitor.NewCheckResult()
-		eMessage := fmt.Sprintf("SNMP target %s failed to return data when measuring metrics. %s", snmpClient.Target, err2)
-		checkResult.SetResult(gomonitor.Critical, eMessage)
-		checkResult.SendResult()
+	if *indexList != "" {
+		indexes, err := ParseIndexes(*indexList)
+		if err != nil {
+			plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid indexes '%s': %s", *indexList, err))
+		}
+		targets = targets[:0]
+		for _, index := range indexes {
+			targets = append(targets, sampleTarget{
+				key: fmt.Sprintf("check_interface_usage_%s_%d", snmpClient.Target, index),
+				get: func() (*interfaces.InterfaceMetrics, error) {
+					return interfaces.GetInterfaceMetrics(&snmpClient, index)
+				},
+			})
+		}
+	}
+
+	if *aliasPattern != "" {
+		pattern, err := regexp.Compile(*aliasPattern)
+		if err != nil {
+			plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid aliasPattern '%s': %s", *aliasPattern, err))
+		}
+		indexes, err := FindInterfacesByAlias(&snmpClient, pattern, *aliasGroup)
+		if err != nil {
+			eMessage := fmt.Sprintf("SNMP target %s failed to return data when resolving aliases. %s", snmpClient.Target, err)
+			plugin.Exit(plugin.ErrorStatus(err), eMessage)
+		}
+		if len(indexes) == 0 {
+			eMessage := fmt.Sprintf("SNMP target %s has no interfaces with an alias matching '%s' in group '%s'", snmpClient.Target, *aliasPattern, *aliasGroup)
+			plugin.Exit(gomonitor.Critical, eMessage)
+		}
+		groupName := *aliasGroup
+		if groupName == "" {
+			groupName = *aliasPattern
+		}
+		targets = []sampleTarget{{
+			key: fmt.Sprintf("check_interface_usage_%s_alias_%s", snmpClient.Target, groupName),
+			get: func() (*interfaces.InterfaceMetrics, error) {
+				return GetAggregateInterfaceMetrics(&snmpClient, groupName, indexes)
+			},
+		}}
+	}
+
+	// Sample every interface, then wait once, or use the saved samples, before sampling them all again
+	measure1, err := sampleAll(targets)
+	if err != nil {
+		eMessage := fmt.Sprintf("SNMP target %s failed to return data when measuring metrics. %s", snmpClient.Target, err)
+		plugin.Exit(plugin.ErrorStatus(err), eMessage)
+	}
+
+	var measure2 []*interfaces.InterfaceMetrics
+	if *stateDir != "" {
+		// Compare against the samples saved by the previous run instead of sleeping
+		var initializing []string
+		for i, target := range targets {
+			previous, err := SwapSavedMetrics(*stateDir, target.key, measure1[i])
+			if err != nil {
+				plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Failed to use state directory %s: %s", *stateDir, err))
+			}
+			if previous == nil {
+				initializing = append(initializing, measure1[i].Name)
+			}
+			measure2 = append(measure2, measure1[i])
+			measure1[i] = previous
+		}
+		if len(initializing) > 0 {
+			plugin.Exit(gomonitor.Unknown, fmt.Sprintf("%s - Initializing, no previous sample saved yet", strings.Join(initializing, ", ")))
+		}
+	} else {
+		// delay
+		time.Sleep(time.Duration(*delay) * time.Second)
+
+		measure2, err = sampleAll(targets)
+		if err != nil {
+			eMessage := fmt.Sprintf("SNMP target %s failed to return data when measuring metrics. %s", snmpClient.Target, err)
+			plugin.Exit(plugin.ErrorStatus(err), eMessage)
+		}
+	}
+
+	if *output == "graphite" {
+		// A failure to reach Carbon is logged to stderr so it does not mask the state of the interface
+		var metrics []graphite.Metric
+		var err error
+		for i := range targets {
+			var interfaceMetrics []graphite.Metric
+			interfaceMetrics, err = interfaces.GraphiteMetrics(*graphitePrefix, snmpClient.Target, *measure1[i], *measure2[i])
+			if err != nil {
+				break
+			}
+			metrics = append(metrics, interfaceMetrics...)
+		}
+		if err == nil {
+			err = graphite.Send(*carbonHost, snmpClient.Timeout, metrics)
+		}
+		if err != nil {
+			slog.Warn("Failed to send metrics to graphite", "carbonHost", *carbonHost, "error", err)
+		}
 	}
 
 	// Calculate current usage and determine thresholds
-	result := DetermineInterfaceUsage(*measure1, *measure2, *warnIn, *warnOut, *critIn, *critOut, *enablePerfData)
+	results := make([]*gomonitor.CheckResult, len(targets))
+	for i := range targets {
+		results[i] = interfaces.DetermineInterfaceUsage(*measure1[i], *measure2[i], thresholds["warnIn"], thresholds["warnOut"], thresholds["critIn"], thresholds["critOut"], *percent, *enablePerfData, time.Duration(*minInterval)*time.Second)
+	}
+	result := results[0]
+	if len(results) > 1 {
+		names := make([]string, len(targets))
+		for i := range targets {
+			names[i] = measure1[i].Name
+		}
+		result = CombineResults(names, results)
+	}
+	if *resolveName {
+		plugin.PrefixSysName(&snmpClient, result)
+	}
 	result.SendResult()
 }
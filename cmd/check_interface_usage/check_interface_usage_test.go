@@ -0,0 +1,116 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"github.com/dmabry/gochecks/internal/interfaces"
+	"github.com/dmabry/gochecks/internal/snmp"
+	"github.com/dmabry/gochecks/internal/snmp/snmptest"
+	"testing"
+	"time"
+)
+
+// memberValues sets the values the agent holds for the interface with the given index. The interface has
+// 64-bit counters unless hc is false.
+func memberValues(values map[string]interface{}, index int, name string, in uint32, hc bool) {
+	oid := func(column string) string { return fmt.Sprintf("%s.%d", column, index) }
+	values[oid(interfaces.OIDIfName)] = name
+	values[oid(interfaces.OIDIfInOctets)] = in
+	values[oid(interfaces.OIDIfOutOctets)] = uint32(0)
+	values[oid(interfaces.OIDIfSpeed)] = uint(1000000000)
+	values[oid(interfaces.OIDIfHighSpeed)] = uint(1000)
+	if hc {
+		values[oid(interfaces.OIDIfHCInOctets)] = uint64(in)
+		values[oid(interfaces.OIDIfHCOutOctets)] = uint64(0)
+	}
+}
+
+func TestGetAggregateInterfaceMetrics(t *testing.T) {
+	tests := []struct {
+		name            string
+		hc              [2]bool
+		wantLowCapacity bool
+	}{
+		{"all 64-bit", [2]bool{true, true}, false},
+		{"mixed", [2]bool{true, false}, false},
+		{"all 32-bit", [2]bool{false, false}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := make(map[string]interface{})
+			memberValues(values, 1, "Gi0/1", 4294967000, tt.hc[0])
+			memberValues(values, 2, "Gi0/2", 3000000000, tt.hc[1])
+			agent := snmptest.NewAgent(t, values)
+			client := snmp.Client{Target: agent.Target, Community: "public", Timeout: time.Second, Retries: -1}
+
+			aggregate, err := GetAggregateInterfaceMetrics(&client, "ISP-A", []int{1, 2})
+			if err != nil {
+				t.Fatalf("GetAggregateInterfaceMetrics returned error: %v", err)
+			}
+			if aggregate.Name != "ISP-A" {
+				t.Errorf("Name = %q, want ISP-A", aggregate.Name)
+			}
+			if len(aggregate.Members) != 2 || aggregate.Members[0].Name != "Gi0/1" || aggregate.Members[1].Name != "Gi0/2" {
+				t.Fatalf("Members = %+v, want Gi0/1 and Gi0/2", aggregate.Members)
+			}
+			if aggregate.Members[0].In != 4294967000 || aggregate.Members[1].In != 3000000000 {
+				t.Errorf("member In = %d and %d, want the counters of each member", aggregate.Members[0].In, aggregate.Members[1].In)
+			}
+			if aggregate.Speed != 2000000000 || aggregate.HighSpeed != 2000 {
+				t.Errorf("Speed = %d, HighSpeed = %d, want the sums 2000000000 and 2000", aggregate.Speed, aggregate.HighSpeed)
+			}
+			if aggregate.LowCapacity != tt.wantLowCapacity {
+				t.Errorf("LowCapacity = %t, want %t", aggregate.LowCapacity, tt.wantLowCapacity)
+			}
+		})
+	}
+}
+
+func TestGetAggregateInterfaceMetricsMissingMember(t *testing.T) {
+	values := make(map[string]interface{})
+	memberValues(values, 1, "Gi0/1", 0, true)
+	agent := snmptest.NewAgent(t, values)
+	client := snmp.Client{Target: agent.Target, Community: "public", Timeout: time.Second, Retries: -1}
+
+	if _, err := GetAggregateInterfaceMetrics(&client, "ISP-A", []int{1, 2}); err == nil {
+		t.Error("GetAggregateInterfaceMetrics returned no error for a member that does not exist")
+	}
+}
+
+func TestSwapSavedMetrics(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	first := &interfaces.InterfaceMetrics{Name: "Gi0/1", HCIn: 1000, Timestamp: start}
+	second := &interfaces.InterfaceMetrics{Name: "Gi0/1", HCIn: 2000, Timestamp: start.Add(time.Minute)}
+
+	previous, err := SwapSavedMetrics(dir, "check_interface_usage_192.0.2.1_1", first)
+	if err != nil {
+		t.Fatalf("first SwapSavedMetrics returned error: %v", err)
+	}
+	if previous != nil {
+		t.Errorf("first SwapSavedMetrics returned %+v, want nil on the first run", *previous)
+	}
+
+	previous, err = SwapSavedMetrics(dir, "check_interface_usage_192.0.2.1_1", second)
+	if err != nil {
+		t.Fatalf("second SwapSavedMetrics returned error: %v", err)
+	}
+	if previous == nil || previous.HCIn != first.HCIn || !previous.Timestamp.Equal(first.Timestamp) {
+		t.Errorf("second SwapSavedMetrics returned %+v, want the metrics of the first run", previous)
+	}
+}
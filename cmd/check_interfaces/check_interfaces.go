@@ -18,18 +18,35 @@ package main
 
 import (
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"github.com/dmabry/gochecks/internal/config"
 	"github.com/dmabry/gochecks/internal/interfaces"
+	"github.com/dmabry/gochecks/internal/plugin"
 	"github.com/dmabry/gochecks/internal/snmp"
+	"github.com/dmabry/gochecks/internal/state"
 	"github.com/dmabry/gomonitor"
-	"log"
+	"log/slog"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// oidSysUpTime is SNMPv2-MIB::sysUpTime.0, the time in timeticks since the agent was last re-initialized.
+const oidSysUpTime = "1.3.6.1.2.1.1.3.0"
+
+// logTypeMismatch logs at debug level that the value of oid is not of the expected type.
+// Agents returning unusual types are common enough that this is not worth a warning on every run.
+func logTypeMismatch(oid string, expected string, value interface{}) {
+	slog.Debug("Unexpected value type", "oid", oid, "expected", expected, "type", fmt.Sprintf("%T", value), "value", value)
+}
+
 // updateInterfaceDetails updates the corresponding field in ifaceDetails based on the provided OID and value.
-// It logs an error message if the value is not of the expected type for the specified OID.
+// It logs a debug message if the value is not of the expected type for the specified OID.
 // Supported OIDs and their expected value types:
 // - interfaces.OIDIfIndex: int
 // - interfaces.OIDIfDescr: []byte
@@ -41,6 +58,7 @@ import (
 // - interfaces.OIDIfAdminStatus: int
 // - interfaces.OIDIfOperStatus: int
 // - interfaces.OIDIfLastChange: uint32
+// - interfaces.OIDDot3StatsDuplexStatus: int
 // - interfaces.OIDIfInOctets: uint
 // - interfaces.OIDIfInUcastPkts: uint
 // - interfaces.OIDIfInDiscards: uint
@@ -64,226 +82,232 @@ func updateInterfaceDetails(ifaceDetails *interfaces.InterfaceDetail, oid string
 		if val, ok := value.(int); ok {
 			ifaceDetails.Index = val
 		} else {
-			log.Printf("Value for OID %s is not of type int: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "int", value)
 		}
 	case interfaces.OIDIfDescr:
 		if val, ok := value.([]byte); ok {
 			ifaceDetails.Description = string(val)
 		} else {
-			log.Printf("Value for OID %s is not of type []byte: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "[]byte", value)
 		}
 	case interfaces.OIDIfType:
 		if val, ok := value.(int); ok {
 			ifaceDetails.Type = val
 		} else {
-			log.Printf("Value for OID %s is not of type int: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "int", value)
 		}
 	case interfaces.OIDIfMTU:
 		if val, ok := value.(int); ok {
 			ifaceDetails.MTU = val
 		} else {
-			log.Printf("Value for OID %s is not of type int: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "int", value)
 		}
 	case interfaces.OIDIfSpeed:
 		if val, ok := value.(uint); ok {
 			ifaceDetails.Speed = val
 		} else {
-			log.Printf("Value for OID %s is not of type uint: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "uint", value)
 		}
 	case interfaces.OIDIfHighSpeed:
 		if val, ok := value.(uint); ok {
 			ifaceDetails.HighSpeed = val
 		} else {
-			log.Printf("Value for OID %s is not of type uint: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "uint", value)
 		}
 	case interfaces.OIDIfPhysAddress:
 		if val, ok := value.([]byte); ok {
 			ifaceDetails.PhysAddress = hex.EncodeToString(val)
 		} else {
-			log.Printf("Value for OID %s is not of type []byte: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "[]byte", value)
 		}
 	case interfaces.OIDIfAdminStatus:
 		if val, ok := value.(int); ok {
 			ifaceDetails.AdminStatus = val
 		} else {
-			log.Printf("Value for OID %s is not of type int: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "int", value)
 		}
 	case interfaces.OIDIfOperStatus:
 		if val, ok := value.(int); ok {
 			ifaceDetails.OperStatus = val
 		} else {
-			log.Printf("Value for OID %s is not of type int: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "int", value)
 		}
 	case interfaces.OIDIfLastChange:
 		if val, ok := value.(uint32); ok {
 			ifaceDetails.LastChange = val
 		} else {
-			log.Printf("Value for OID %s is not of type uint32: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "uint32", value)
+		}
+	case interfaces.OIDDot3StatsDuplexStatus:
+		if val, ok := value.(int); ok {
+			ifaceDetails.Duplex = val
+		} else {
+			logTypeMismatch(oid, "int", value)
 		}
 	case interfaces.OIDIfInOctets:
 		if val, ok := value.(uint); ok {
 			ifaceDetails.InOctets = val
 		} else {
-			log.Printf("Value for OID %s is not of type uint: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "uint", value)
 		}
 	case interfaces.OIDIfInUcastPkts:
 		if val, ok := value.(uint); ok {
 			ifaceDetails.InUcastPkts = val
 		} else {
-			log.Printf("Value for OID %s is not of type uint: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "uint", value)
 		}
 	case interfaces.OIDIfInDiscards:
 		if val, ok := value.(uint); ok {
 			ifaceDetails.InDiscards = val
 		} else {
-			log.Printf("Value for OID %s is not of type uint: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "uint", value)
 		}
 	case interfaces.OIDIfInErrors:
 		if val, ok := value.(uint); ok {
 			ifaceDetails.InErrors = val
 		} else {
-			log.Printf("Value for OID %s is not of type uint: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "uint", value)
 		}
 	case interfaces.OIDIfOutOctets:
 		if val, ok := value.(uint); ok {
 			ifaceDetails.OutOctets = val
 		} else {
-			log.Printf("Value for OID %s is not of type uint: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "uint", value)
 		}
 	case interfaces.OIDIfOutUcastPkts:
 		if val, ok := value.(uint); ok {
 			ifaceDetails.OutUcastPkts = val
 		} else {
-			log.Printf("Value for OID %s is not of type uint: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "uint", value)
 		}
 	case interfaces.OIDIfOutDiscards:
 		if val, ok := value.(uint); ok {
 			ifaceDetails.OutDiscards = val
 		} else {
-			log.Printf("Value for OID %s is not of type uint: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "uint", value)
 		}
 	case interfaces.OIDIfOutErrors:
 		if val, ok := value.(uint); ok {
 			ifaceDetails.OutErrors = val
 		} else {
-			log.Printf("Value for OID %s is not of type uint: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "uint", value)
 		}
 	case interfaces.OIDIfOutNUcastPkts:
 		if val, ok := value.(uint); ok {
 			ifaceDetails.OutNUcastPkts = val
 		} else {
-			log.Printf("Value for OID %s is not of type uint: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "uint", value)
 		}
 	case interfaces.OIDIfName:
 		if val, ok := value.([]byte); ok {
 			ifaceDetails.Name = string(val)
 		} else {
-			log.Printf("Value for OID %s is not of type []byte: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "[]byte", value)
 		}
 	case interfaces.OIDIfAlias:
 		if val, ok := value.([]byte); ok {
 			ifaceDetails.Alias = string(val)
 		} else {
-			log.Printf("Value for OID %s is not of type []byte: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "[]byte", value)
 		}
 	case interfaces.OIDIfHCInOctets:
 		if val, ok := value.(uint64); ok {
 			ifaceDetails.HCInOctets = val
 		} else {
-			log.Printf("Value for OID %s is not of type uint64: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "uint64", value)
 		}
 	case interfaces.OIDIfHCOutOctets:
 		if val, ok := value.(uint64); ok {
 			ifaceDetails.HCOutOctets = val
 		} else {
-			log.Printf("Value for OID %s is not of type uint64: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "uint64", value)
 		}
 	case interfaces.OIDIfHCInUcastPkts:
 		if val, ok := value.(uint64); ok {
 			ifaceDetails.HCInUcastPkts = val
 		} else {
-			log.Printf("Value for OID %s is not of type uint64: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "uint64", value)
 		}
 	case interfaces.OIDIfHCOutUcastPkts:
 		if val, ok := value.(uint64); ok {
 			ifaceDetails.HCOutUcastPkts = val
 		} else {
-			log.Printf("Value for OID %s is not of type uint64: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "uint64", value)
 		}
 	case interfaces.OIDIfHCInMulticastPkts:
 		if val, ok := value.(uint64); ok {
 			ifaceDetails.HCInMulticastPkts = val
 		} else {
-			log.Printf("Value for OID %s is not of type uint64: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "uint64", value)
 		}
 	case interfaces.OIDIfHCInBroadcastPkts:
 		if val, ok := value.(uint64); ok {
 			ifaceDetails.HCInBroadcastPkts = val
 		} else {
-			log.Printf("Value for OID %s is not of type uint64: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "uint64", value)
 		}
 	case interfaces.OIDIfHCOutBroadcastPkts:
 		if val, ok := value.(uint64); ok {
 			ifaceDetails.HCOutBroadcastPkts = val
 		} else {
-			log.Printf("Value for OID %s is not of type uint64: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "uint64", value)
 		}
 	case interfaces.OIDIfLinkUpDownTrapEnable:
 		if val, ok := value.(int); ok {
 			ifaceDetails.LinkUpDownTrapEnable = val
 		} else {
-			log.Printf("Value for OID %s is not of type int: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "int", value)
 		}
 	case interfaces.OIDIfConnectorPresent:
 		if val, ok := value.(int); ok {
 			ifaceDetails.ConnectorPresent = val
 		} else {
-			log.Printf("Value for OID %s is not of type int: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "int", value)
 		}
 	case interfaces.OIDIfOutBroadcastPkts:
 		if val, ok := value.(uint); ok {
 			ifaceDetails.OutBroadcastPkts = val
 		} else {
-			log.Printf("Value for OID %s is not of type uint: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "uint", value)
 		}
 	case interfaces.OIDIfInBroadcastPkts:
 		if val, ok := value.(uint); ok {
 			ifaceDetails.OutBroadcastPkts = val
 		} else {
-			log.Printf("Value for OID %s is not of type uint: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "uint", value)
 		}
 	case interfaces.OIDIfCounterDiscontinuityTime:
 		if val, ok := value.(uint32); ok {
 			ifaceDetails.CounterDiscontinuityTime = val
 		} else {
-			log.Printf("Value for OID %s is not of type uint32: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "uint32", value)
 		}
 	case interfaces.OIDIfHCOutMulticastPkts:
 		if val, ok := value.(uint64); ok {
 			ifaceDetails.HCOutMulticastPkts = val
 		} else {
-			log.Printf("Value for OID %s is not of type uint64: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "uint64", value)
 		}
 	case interfaces.OIDIfInMulticastPkts:
 		if val, ok := value.(uint); ok {
 			ifaceDetails.InMulticastPkts = val
 		} else {
-			log.Printf("Value for OID %s is not of type uint: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "uint", value)
 		}
 	case interfaces.OIDIfOutMulticastPkts:
 		if val, ok := value.(uint); ok {
 			ifaceDetails.OutMulticastPkts = val
 		} else {
-			log.Printf("Value for OID %s is not of type uint: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "uint", value)
 		}
 	case interfaces.OIDIfPromiscuousMode:
 		if val, ok := value.(int); ok {
 			ifaceDetails.PromiscuousMode = val
 		} else {
-			log.Printf("Value for OID %s is not of type int: %T -> %v\n", oid, value, value)
+			logTypeMismatch(oid, "int", value)
 		}
 	default:
-		log.Printf("Unknown Type: OID: %s - %T -> %v\n", oid, value, value)
+		slog.Debug("Unhandled OID", "oid", oid, "type", fmt.Sprintf("%T", value), "value", value)
 	}
 }
 
@@ -311,39 +335,348 @@ func buildInterfaceDetailsMessage(interfaces map[int]*interfaces.InterfaceDetail
 	return message.String()
 }
 
-// CheckInterfaceMetrics retrieves interface details from the target SNMP device using the provided SNMP client.
-// It walks the IF-MIB::ifEntry and ifXTable OIDs to gather information about each interface.
-// The function populates an InterfaceDetail structure for each interface encountered and builds a message
-// with the interface details. If any error occurs during the SNMP request, it will set the result to Critical
-// and return the error message along with the check result. Otherwise, it sets the result to OK and returns
-// the interface details message along with the check result.
-func CheckInterfaceMetrics(snmpClient *snmp.Client) *gomonitor.CheckResult {
-	baseOIDs := []string{"1.3.6.1.2.1.2.2", "1.3.6.1.2.1.31.1.1.1"} // IF-MIB::ifEntry and ifXTable OIDs
+// interfacesOutput is the JSON object reported as the result message when the output is "json".
+type interfacesOutput struct {
+	Problems   []string                      `json:"problems"`
+	Interfaces []*interfaces.InterfaceDetail `json:"interfaces"`
+}
 
-	// Prepare data structure for holding interface details
-	deviceInterfaces := make(map[int]*interfaces.InterfaceDetail)
+// buildInterfaceDetailsJSON marshals the problems found by the check and the interface details, ordered by
+// interface index, to a JSON object. Problems is an empty array when the check found none.
+// The index of each interface is taken from the map key so it is present even when the agent did not return ifIndex.
+//
+// Parameters:
+//   - deviceInterfaces: A map of interface details keyed by interface index.
+//   - problems: The problems found by the check, reported in the text output as the message.
+//
+// Returns:
+//   - message: The JSON object of problems and interface details.
+//   - err: An error if the details could not be marshaled.
+func buildInterfaceDetailsJSON(deviceInterfaces map[int]*interfaces.InterfaceDetail, problems []string) (string, error) {
+	details := make([]*interfaces.InterfaceDetail, 0, len(deviceInterfaces))
+	for _, index := range sortedIndexes(deviceInterfaces) {
+		iface := deviceInterfaces[index]
+		iface.Index = index
+		details = append(details, iface)
+	}
+	if problems == nil {
+		problems = []string{}
+	}
+	jsonBytes, err := json.Marshal(interfacesOutput{Problems: problems, Interfaces: details})
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
+}
+
+// setJSONResult sets status on checkResult with the JSON object of problems and interface details built by
+// buildInterfaceDetailsJSON as the message, or Unknown with the JSON error object when it cannot be built.
+func setJSONResult(checkResult *gomonitor.CheckResult, status gomonitor.ExitCode, target string, deviceInterfaces map[int]*interfaces.InterfaceDetail, problems []string) {
+	message, err := buildInterfaceDetailsJSON(deviceInterfaces, problems)
+	if err != nil {
+		setError(checkResult, gomonitor.Unknown, "json", target, "", fmt.Sprintf("failed to marshal interface details: %v", err))
+		return
+	}
+	checkResult.SetResult(status, message)
+}
+
+// sortedIndexes returns the indexes of the interfaces in ascending order, so results list interfaces
+// deterministically.
+func sortedIndexes(deviceInterfaces map[int]*interfaces.InterfaceDetail) []int {
+	indexes := make([]int, 0, len(deviceInterfaces))
+	for index := range deviceInterfaces {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
+
+// evaluateErrorThresholds compares the error (InErrors+OutErrors) and discard (InDiscards+OutDiscards)
+// counts of each interface against the thresholds in the options. A threshold of 0 is disabled.
+// When ErrorRates is set in the options, their per-second rates are compared instead, and interfaces
+// without a rate, e.g. ones that appeared since the previous sample, are skipped.
+//
+// Parameters:
+//   - deviceInterfaces: A map of interface details keyed by interface index.
+//   - rates: The error and discard rates keyed by interface index, used when ErrorRates is set.
+//   - options: The check options holding the error and discard thresholds.
+//
+// Returns:
+//   - status: The worst status of all interfaces, OK when no threshold is exceeded.
+//   - problems: A description of each interface exceeding a threshold.
+func evaluateErrorThresholds(deviceInterfaces map[int]*interfaces.InterfaceDetail, rates map[int]interfaces.ErrorRates, options CheckOptions) (gomonitor.ExitCode, []string) {
+	status := gomonitor.OK
+	var problems []string
+	for _, index := range sortedIndexes(deviceInterfaces) {
+		iface := deviceInterfaces[index]
+		var errorValue, discardValue float64
+		var detail string
+		if options.ErrorRates {
+			rate, ok := rates[index]
+			if !ok {
+				continue
+			}
+			errorValue, discardValue = rate.Errors, rate.Discards
+			detail = fmt.Sprintf("errors: %.2f/s discards: %.2f/s", errorValue, discardValue)
+		} else {
+			errorCount := iface.InErrors + iface.OutErrors
+			discardCount := iface.InDiscards + iface.OutDiscards
+			errorValue, discardValue = float64(errorCount), float64(discardCount)
+			detail = fmt.Sprintf("errors: %d discards: %d", errorCount, discardCount)
+		}
+
+		ifaceStatus := gomonitor.OK
+		if options.CritErrors > 0 && errorValue > options.CritErrors || options.CritDiscards > 0 && discardValue > options.CritDiscards {
+			ifaceStatus = gomonitor.Critical
+		} else if options.WarnErrors > 0 && errorValue > options.WarnErrors || options.WarnDiscards > 0 && discardValue > options.WarnDiscards {
+			ifaceStatus = gomonitor.Warning
+		}
+		if ifaceStatus != gomonitor.OK {
+			problems = append(problems, fmt.Sprintf("%s (index %d) %s", iface.Name, index, detail))
+			status = plugin.WorseStatus(status, ifaceStatus)
+		}
+	}
+	return status, problems
+}
+
+// evaluateOperStatus flags interfaces that are administratively up but operationally down.
+// Administratively down interfaces are ignored, as are interfaces whose alias matches IgnoreAlias in the options.
+//
+// Parameters:
+//   - deviceInterfaces: A map of interface details keyed by interface index.
+//   - options: The check options holding the IgnoreAlias pattern.
+//
+// Returns:
+//   - status: Critical when any interface is admin-up/oper-down, otherwise OK.
+//   - problems: A description of each affected interface with its name and alias.
+func evaluateOperStatus(deviceInterfaces map[int]*interfaces.InterfaceDetail, options CheckOptions) (gomonitor.ExitCode, []string) {
+	status := gomonitor.OK
+	var problems []string
+	for _, index := range sortedIndexes(deviceInterfaces) {
+		iface := deviceInterfaces[index]
+		if !iface.IsAdminUpOperDown() {
+			continue
+		}
+		if options.IgnoreAlias != nil && options.IgnoreAlias.MatchString(iface.Alias) {
+			continue
+		}
+		problems = append(problems, fmt.Sprintf("%s (index %d, alias '%s') admin up but oper status %s", iface.Name, index, iface.Alias, interfaces.IfStatusName(iface.OperStatus)))
+		status = gomonitor.Critical
+	}
+	return status, problems
+}
+
+// evaluateFlaps flags interfaces whose ifLastChange falls within the flap window.
+// ifLastChange is the value of sysUpTime at the last state change, so the age of the change is
+// sysUpTime minus ifLastChange. Interfaces with an ifLastChange of 0 last changed before the agent
+// was initialized and are ignored, as are values ahead of sysUpTime after a timeticks wrap.
+//
+// Parameters:
+//   - deviceInterfaces: A map of interface details keyed by interface index.
+//   - sysUpTime: The agent's sysUpTime in timeticks (hundredths of a second).
+//   - window: How recent a state change must be to count as a flap.
+//
+// Returns:
+//   - status: Warning when any interface changed state within the window, otherwise OK.
+//   - problems: A description of each interface that flapped with how long ago it changed.
+func evaluateFlaps(deviceInterfaces map[int]*interfaces.InterfaceDetail, sysUpTime uint32, window time.Duration) (gomonitor.ExitCode, []string) {
+	status := gomonitor.OK
+	var problems []string
+	for _, index := range sortedIndexes(deviceInterfaces) {
+		iface := deviceInterfaces[index]
+		if iface.LastChange == 0 || iface.LastChange > sysUpTime {
+			continue
+		}
+		age := time.Duration(sysUpTime-iface.LastChange) * 10 * time.Millisecond
+		if age > window {
+			continue
+		}
+		problems = append(problems, fmt.Sprintf("%s (index %d) changed state %s ago", iface.Name, index, age.Round(time.Second)))
+		status = gomonitor.Warning
+	}
+	return status, problems
+}
 
+// CheckConnectorStatus correlates ifConnectorPresent with ifOperStatus for each interface to tell
+// likely faults apart from unused ports. An enabled interface that is down with a connector plugged in
+// is reported as Critical, while interfaces that are down with no connector are expected and only counted.
+//
+// Parameters:
+//   - deviceInterfaces: A map of interface details keyed by interface index.
+//
+// Returns:
+//   - checkResult: Critical listing the faulted interfaces, otherwise OK.
+func CheckConnectorStatus(deviceInterfaces map[int]*interfaces.InterfaceDetail) *gomonitor.CheckResult {
 	checkResult := gomonitor.NewCheckResult()
 
-	for _, baseOID := range baseOIDs {
-		result, _, err := snmpClient.Walk(baseOID)
+	var faults []string
+	unplugged := 0
+	for _, index := range sortedIndexes(deviceInterfaces) {
+		iface := deviceInterfaces[index]
+		if iface.IsLinkFault() {
+			faults = append(faults, fmt.Sprintf("%s (index %d)", iface.Name, index))
+		} else if iface.IsUnplugged() {
+			unplugged++
+		}
+	}
+
+	if len(faults) > 0 {
+		message := fmt.Sprintf("Connector present but link down on %d interface(s): %s. %d interface(s) down with no connector", len(faults), strings.Join(faults, ", "), unplugged)
+		checkResult.SetResult(gomonitor.Critical, message)
+		return checkResult
+	}
+	message := fmt.Sprintf("No interfaces down with a connector present. %d interface(s) down with no connector", unplugged)
+	checkResult.SetResult(gomonitor.OK, message)
+	return checkResult
+}
+
+// CheckOptions controls which interfaces CheckInterfaceMetrics reports on and how they are evaluated.
+type CheckOptions struct {
+	// Filter, when set, limits the check to interfaces whose FilterField matches it.
+	// Non-matching interfaces are omitted entirely.
+	Filter *regexp.Regexp
+	// FilterField is the interface field Filter is matched against: name, descr or alias.
+	FilterField string
+	// CheckConnector returns the result of CheckConnectorStatus instead of the interface details. With the "json"
+	// output, its message is reported as the only problem when it is not OK.
+	CheckConnector bool
+	// WarnErrors and CritErrors are the thresholds for InErrors+OutErrors of an interface, or for its per-second
+	// rate when ErrorRates is set. 0 disables them.
+	WarnErrors float64
+	CritErrors float64
+	// WarnDiscards and CritDiscards are the thresholds for InDiscards+OutDiscards of an interface, or for its
+	// per-second rate when ErrorRates is set. 0 disables them.
+	WarnDiscards float64
+	CritDiscards float64
+	// ErrorRates compares the error and discard thresholds against per-second rates, computed against the
+	// counters saved in StateDir by the previous run, instead of the cumulative counters.
+	ErrorRates bool
+	// StateDir is the directory the error and discard counters are saved in between runs when ErrorRates is set.
+	StateDir string
+	// CheckOperStatus flags interfaces that are administratively up but operationally down as Critical.
+	CheckOperStatus bool
+	// IgnoreAlias, when set, excludes interfaces whose alias matches it from the oper status check,
+	// e.g. spare or unused ports that are known to be down.
+	IgnoreAlias *regexp.Regexp
+	// FlapWindow, when non-zero, flags interfaces whose last state change happened within it as Warning.
+	FlapWindow time.Duration
+	// Verbose appends the details of every interface to the result message.
+	Verbose bool
+	// Output selects the result message format: "text" (the default) or "json", which replaces the
+	// message with the JSON object of problems and interface details built by buildInterfaceDetailsJSON,
+	// or with a JSON object of the status, message, target and OID when the check fails.
+	Output string
+}
+
+// errorThresholds reports whether any error or discard threshold is set.
+func (options CheckOptions) errorThresholds() bool {
+	return options.WarnErrors > 0 || options.CritErrors > 0 || options.WarnDiscards > 0 || options.CritDiscards > 0
+}
+
+// filterInterfaces removes the interfaces whose field does not match the pattern from deviceInterfaces.
+// It returns an error if the field is not one of name, descr or alias.
+func filterInterfaces(deviceInterfaces map[int]*interfaces.InterfaceDetail, pattern *regexp.Regexp, field string) error {
+	for index, iface := range deviceInterfaces {
+		value, err := iface.TextField(field)
 		if err != nil {
-			eMessage := fmt.Sprintf("SNMP target %s failed to return data for requested OID: %w", snmpClient.Target, err)
-			checkResult.SetResult(gomonitor.Critical, eMessage)
-			return checkResult
+			return err
+		}
+		if !pattern.MatchString(value) {
+			delete(deviceInterfaces, index)
+		}
+	}
+	return nil
+}
+
+// errorOutput is the JSON object reported instead of the error message when the output is "json".
+type errorOutput struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Target  string `json:"target"`
+	OID     string `json:"oid,omitempty"`
+}
+
+// setError sets the status and message of a failed check on checkResult. When output is "json", the message is
+// replaced by a JSON object of the status, message, target and OID, so consumers can branch on the status
+// without parsing the text. An empty oid is left out.
+func setError(checkResult *gomonitor.CheckResult, status gomonitor.ExitCode, output string, target string, oid string, message string) {
+	if output == "json" {
+		jsonBytes, err := json.Marshal(errorOutput{Status: status.String(), Message: message, Target: target, OID: oid})
+		if err == nil {
+			message = string(jsonBytes)
+		}
+	}
+	checkResult.SetResult(status, message)
+}
+
+// collectError is a failure to collect the interfaces, along with the status the check reports for it and the
+// OID involved, if any. It unwraps to the underlying error, so SNMP timeouts are still retried.
+type collectError struct {
+	status  gomonitor.ExitCode
+	oid     string
+	message string
+	err     error
+}
+
+// Error returns the message reported by the check.
+func (e *collectError) Error() string {
+	return e.message
+}
+
+// Unwrap returns the underlying error.
+func (e *collectError) Unwrap() error {
+	return e.err
+}
+
+// collectedInterfaces holds the interfaces and sysUpTime read by collectInterfaces.
+type collectedInterfaces struct {
+	interfaces map[int]*interfaces.InterfaceDetail
+	sysUpTime  uint32
+}
+
+// collectInterfaces walks the IF-MIB::ifEntry and ifXTable OIDs over session and returns an InterfaceDetail for
+// each interface encountered. When readSysUpTime is set, sysUpTime is read as well. Failures are returned as a
+// *collectError naming the target and OID.
+func collectInterfaces(session *snmp.Session, target string, readSysUpTime bool) (collectedInterfaces, error) {
+	// IF-MIB::ifEntry and ifXEntry are walked whole, while only the EtherLike-MIB::dot3StatsDuplexStatus column,
+	// whose dot3StatsIndex is the ifIndex, is needed from dot3StatsEntry
+	walks := []struct {
+		oid   string
+		entry bool
+	}{
+		{".1.3.6.1.2.1.2.2.1", true},
+		{".1.3.6.1.2.1.31.1.1.1", true},
+		{interfaces.OIDDot3StatsDuplexStatus, false},
+	}
+
+	// Prepare data structure for holding interface details
+	deviceInterfaces := make(map[int]*interfaces.InterfaceDetail)
+
+	for _, walk := range walks {
+		result, _, err := session.Walk(walk.oid)
+		if err != nil {
+			eMessage := fmt.Sprintf("SNMP target %s failed to return data for requested OID: %v", target, err)
+			return collectedInterfaces{}, &collectError{status: plugin.ErrorStatus(err), oid: walk.oid, message: eMessage, err: err}
 		}
 		for oid, value := range result {
-			fields := strings.Split(oid, ".")
-			// The index for each interface
-			index, err2 := strconv.Atoi(fields[len(fields)-1])
+			// Split the OID into the column OID and the index of the interface
+			columnOid, rawIndex := walk.oid, ""
+			var err2 error
+			if walk.entry {
+				var column int
+				column, rawIndex, err2 = snmp.ColumnIndex(walk.oid, oid)
+				columnOid = fmt.Sprintf("%s.%d", walk.oid, column)
+			} else {
+				rawIndex, err2 = snmp.TableIndex(walk.oid, oid)
+			}
 			if err2 != nil {
-				eMessage := fmt.Sprintf("failed to convert interface index to int: %w", err2)
-				checkResult.SetResult(gomonitor.Critical, eMessage)
-				return checkResult
+				return collectedInterfaces{}, &collectError{status: gomonitor.Critical, oid: oid, message: err2.Error(), err: err2}
+			}
+			index, err2 := strconv.Atoi(rawIndex)
+			if err2 != nil {
+				eMessage := fmt.Sprintf("failed to convert interface index to int: %v", err2)
+				return collectedInterfaces{}, &collectError{status: gomonitor.Critical, oid: oid, message: eMessage, err: err2}
 			}
-
-			// Remove the index from the OID
-			oidWithoutIndex := strings.Join(fields[:len(fields)-1], ".")
 
 			// Prepare each interface for holding details
 			if _, ok := deviceInterfaces[index]; !ok {
@@ -351,13 +684,146 @@ func CheckInterfaceMetrics(snmpClient *snmp.Client) *gomonitor.CheckResult {
 			}
 
 			ifaceDetails := deviceInterfaces[index]
-			// Match on the complete OID, excluding the index
-			updateInterfaceDetails(ifaceDetails, oidWithoutIndex, value)
+			// Match on the column OID, excluding the index
+			updateInterfaceDetails(ifaceDetails, columnOid, value)
 		}
 	}
 
-	message := buildInterfaceDetailsMessage(deviceInterfaces)
-	checkResult.SetResult(gomonitor.OK, message)
+	collected := collectedInterfaces{interfaces: deviceInterfaces}
+	if readSysUpTime {
+		result, _, err := session.Get([]string{oidSysUpTime})
+		if err != nil {
+			eMessage := fmt.Sprintf("SNMP target %s failed to return sysUpTime: %v", target, err)
+			return collectedInterfaces{}, &collectError{status: plugin.ErrorStatus(err), oid: oidSysUpTime, message: eMessage, err: err}
+		}
+		if len(result.Variables) == 0 {
+			eMessage := fmt.Sprintf("SNMP target %s returned no sysUpTime", target)
+			return collectedInterfaces{}, &collectError{status: gomonitor.Unknown, oid: oidSysUpTime, message: eMessage}
+		}
+		val, ok := result.Variables[0].Value.(uint32)
+		if !ok {
+			eMessage := fmt.Sprintf("SNMP target %s returned an unexpected sysUpTime: %v", target, result.Variables[0].Value)
+			return collectedInterfaces{}, &collectError{status: gomonitor.Unknown, oid: oidSysUpTime, message: eMessage}
+		}
+		collected.sysUpTime = val
+	}
+	return collected, nil
+}
+
+// CheckInterfaceMetrics retrieves interface details from the target SNMP device using the provided SNMP client.
+// It walks the IF-MIB::ifEntry and ifXTable OIDs over a single session to gather information about each interface,
+// attempting the whole collection again on a timeout as configured by the client's Attempts.
+// The function populates an InterfaceDetail structure for each interface encountered and builds a message
+// with the interface details. If any error occurs during the SNMP request, it will set the result to Unknown on a timeout or Critical otherwise,
+// and return the error message along with the check result. Otherwise, the error and discard counts of each
+// interface are evaluated against the thresholds in the options, setting Warning or Critical and naming the
+// offending interfaces when exceeded. When ErrorRates is set, their per-second rates, computed against the
+// counters saved in StateDir by the previous run, are evaluated instead, and the first run only saves the
+// counters and reports Unknown for the rates. When CheckOperStatus is set, interfaces that are administratively up but
+// operationally down are reported as Critical. When FlapWindow is set, sysUpTime is read as well and
+// interfaces that changed state within the window are reported as Warning. The interface details are appended to the message when Verbose is set.
+// The options select the interfaces to include and whether connector faults are evaluated instead.
+// With the "json" output, the problems and interface details are reported together as a JSON object.
+func CheckInterfaceMetrics(snmpClient *snmp.Client, options CheckOptions) *gomonitor.CheckResult {
+	checkResult := gomonitor.NewCheckResult()
+
+	collected, err := snmp.WithSession(snmpClient, func(session *snmp.Session) (collectedInterfaces, error) {
+		return collectInterfaces(session, snmpClient.Target, options.FlapWindow > 0)
+	})
+	if err != nil {
+		var collectErr *collectError
+		if errors.As(err, &collectErr) {
+			setError(checkResult, collectErr.status, options.Output, snmpClient.Target, collectErr.oid, collectErr.message)
+			return checkResult
+		}
+		eMessage := fmt.Sprintf("SNMP target %s failed to connect: %v", snmpClient.Target, err)
+		setError(checkResult, plugin.ErrorStatus(err), options.Output, snmpClient.Target, "", eMessage)
+		return checkResult
+	}
+	deviceInterfaces, sysUpTime := collected.interfaces, collected.sysUpTime
+
+	// Rates are computed against the counters of every interface saved by the previous run, before filtering
+	var rates map[int]interfaces.ErrorRates
+	if options.ErrorRates && options.errorThresholds() {
+		key := "check_interfaces_" + snmpClient.Target
+		sample := interfaces.NewErrorSample(deviceInterfaces)
+		var previous interfaces.ErrorSample
+		found, err := state.Load(options.StateDir, key, &previous)
+		if err == nil {
+			err = state.Save(options.StateDir, key, sample)
+		}
+		if err != nil {
+			setError(checkResult, gomonitor.Unknown, options.Output, snmpClient.Target, "", fmt.Sprintf("Failed to use state directory %s: %v", options.StateDir, err))
+			return checkResult
+		}
+		if found {
+			rates, err = sample.ErrorRates(previous)
+			if err != nil {
+				setError(checkResult, gomonitor.Unknown, options.Output, snmpClient.Target, "", err.Error())
+				return checkResult
+			}
+		}
+	}
+
+	if options.Filter != nil {
+		if err := filterInterfaces(deviceInterfaces, options.Filter, options.FilterField); err != nil {
+			setError(checkResult, gomonitor.Unknown, options.Output, snmpClient.Target, "", err.Error())
+			return checkResult
+		}
+	}
+
+	if options.CheckConnector {
+		connectorResult := CheckConnectorStatus(deviceInterfaces)
+		if options.Output != "json" {
+			return connectorResult
+		}
+		var problems []string
+		if connectorResult.ExitCode != gomonitor.OK {
+			problems = append(problems, connectorResult.Message)
+		}
+		setJSONResult(checkResult, connectorResult.ExitCode, snmpClient.Target, deviceInterfaces, problems)
+		return checkResult
+	}
+
+	var problems []string
+	status := gomonitor.OK
+	if options.ErrorRates && options.errorThresholds() && rates == nil {
+		status = gomonitor.Unknown
+		problems = append(problems, "Error/discard rates initializing, no previous sample saved yet")
+	} else if options.errorThresholds() {
+		var errorProblems []string
+		status, errorProblems = evaluateErrorThresholds(deviceInterfaces, rates, options)
+		if len(errorProblems) > 0 {
+			problems = append(problems, fmt.Sprintf("Interfaces exceeding error/discard thresholds: %s", strings.Join(errorProblems, ", ")))
+		}
+	}
+	if options.CheckOperStatus {
+		operStatus, operProblems := evaluateOperStatus(deviceInterfaces, options)
+		status = plugin.WorseStatus(status, operStatus)
+		if len(operProblems) > 0 {
+			problems = append(problems, fmt.Sprintf("Interfaces down: %s", strings.Join(operProblems, ", ")))
+		}
+	}
+	if options.FlapWindow > 0 {
+		flapStatus, flapProblems := evaluateFlaps(deviceInterfaces, sysUpTime, options.FlapWindow)
+		status = plugin.WorseStatus(status, flapStatus)
+		if len(flapProblems) > 0 {
+			problems = append(problems, fmt.Sprintf("Interfaces flapped: %s", strings.Join(flapProblems, ", ")))
+		}
+	}
+
+	if options.Output == "json" {
+		setJSONResult(checkResult, status, snmpClient.Target, deviceInterfaces, problems)
+		return checkResult
+	}
+	message := fmt.Sprintf("%d interface(s) checked", len(deviceInterfaces))
+	if len(problems) > 0 {
+		message = strings.Join(problems, "; ")
+	}
+	if options.Verbose {
+		message += "\n" + buildInterfaceDetailsMessage(deviceInterfaces)
+	}
+	checkResult.SetResult(status, message)
 	return checkResult
 }
 
@@ -365,16 +831,72 @@ func CheckInterfaceMetrics(snmpClient *snmp.Client) *gomonitor.CheckResult {
 // and performs a check on the target SNMP device using the CheckInterfaceMetrics function.
 // The result of the check is then sent using the SendResult method.
 func main() {
-	target := flag.String("target", "127.0.0.1", "The target SNMP device.")
-	community := flag.String("community", "public", "The SNMP community string.")
+	snmpFlags := snmp.Flags{}
+	snmpFlags.Register()
+	checkConnector := flag.Bool("checkConnector", false, "Alert on interfaces that are down with a connector present instead of listing interface details. Default is false.")
+	filter := flag.String("filter", "", "Regex an interface field must match for the interface to be included, e.g. ^(Gi|Te|Hu). If not provided, all interfaces are included.")
+	filterField := flag.String("filterField", "name", "The interface field -filter is matched against: name, descr or alias.")
+	warnErrors := flag.Float64("warnErrors", 0, "Warning level for in+out errors on any interface, or errors per second with -errorRates. Default is 0 (disabled).")
+	critErrors := flag.Float64("critErrors", 0, "Critical level for in+out errors on any interface, or errors per second with -errorRates. Default is 0 (disabled).")
+	warnDiscards := flag.Float64("warnDiscards", 0, "Warning level for in+out discards on any interface, or discards per second with -errorRates. Default is 0 (disabled).")
+	critDiscards := flag.Float64("critDiscards", 0, "Critical level for in+out discards on any interface, or discards per second with -errorRates. Default is 0 (disabled).")
+	errorRates := flag.Bool("errorRates", false, "Compare the error and discard thresholds against per-second rates since the previous run instead of the cumulative counters. Requires -stateDir. Default is false.")
+	stateDir := flag.String("stateDir", "", "Directory to save the error and discard counters in between runs for -errorRates.")
+	checkOperStatus := flag.Bool("checkOperStatus", false, "Alert on interfaces that are administratively up but operationally down. Default is false.")
+	ignoreAlias := flag.String("ignoreAlias", "", "Regex of interface aliases to exclude from -checkOperStatus, e.g. (?i)spare|unused.")
+	flapWindow := flag.Duration("flapWindow", 0, "Warn on interfaces that changed state within this window, e.g. 15m. Default is 0 (disabled).")
+	output := flag.String("output", "text", "The output format of the interface details and of errors, text or json.")
+	resolveName := flag.Bool("resolveName", false, "Prefix the result message with the sysName of the target. Ignored with -output json. Default is false.")
+	verbose := flag.Bool("verbose", false, "Include the details of every interface in the output. Default is false.")
 	// enablePerfData := flag.Bool("enablePerfData", false, "Enable performance data. Default is false.")
-	flag.Parse()
-
-	snmpClient := snmp.Client{
-		Target:    *target,
-		Community: *community,
+	if err := config.Parse(); err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
 	}
-	result := CheckInterfaceMetrics(&snmpClient)
 
+	snmpClient, err := snmpFlags.Client()
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+	if snmpFlags.Validate {
+		plugin.Validate(&snmpClient)
+	}
+	options := CheckOptions{
+		FilterField:     *filterField,
+		CheckConnector:  *checkConnector,
+		WarnErrors:      *warnErrors,
+		CritErrors:      *critErrors,
+		WarnDiscards:    *warnDiscards,
+		CritDiscards:    *critDiscards,
+		ErrorRates:      *errorRates,
+		StateDir:        *stateDir,
+		CheckOperStatus: *checkOperStatus,
+		FlapWindow:      *flapWindow,
+		Verbose:         *verbose,
+		Output:          *output,
+	}
+	if *output != "text" && *output != "json" {
+		plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid output '%s', expected text or json", *output))
+	}
+	if *errorRates && *stateDir == "" {
+		plugin.Exit(gomonitor.Unknown, "-errorRates requires -stateDir")
+	}
+	if *filter != "" {
+		pattern, err := regexp.Compile(*filter)
+		if err != nil {
+			plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid filter '%s': %s", *filter, err))
+		}
+		options.Filter = pattern
+	}
+	if *ignoreAlias != "" {
+		pattern, err := regexp.Compile(*ignoreAlias)
+		if err != nil {
+			plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid ignoreAlias '%s': %s", *ignoreAlias, err))
+		}
+		options.IgnoreAlias = pattern
+	}
+	result := CheckInterfaceMetrics(&snmpClient, options)
+	if *resolveName && *output != "json" {
+		plugin.PrefixSysName(&snmpClient, result)
+	}
 	result.SendResult()
 }
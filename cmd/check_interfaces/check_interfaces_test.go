@@ -0,0 +1,158 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dmabry/gochecks/internal/interfaces"
+	"github.com/dmabry/gochecks/internal/snmp"
+	"github.com/dmabry/gochecks/internal/snmp/snmptest"
+	"github.com/dmabry/gomonitor"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckConnectorStatus(t *testing.T) {
+	up := &interfaces.InterfaceDetail{Name: "Gi0/1", AdminStatus: interfaces.StatusUp, OperStatus: interfaces.StatusUp,
+		ConnectorPresent: interfaces.TruthValueTrue}
+	fault := &interfaces.InterfaceDetail{Name: "Gi0/2", AdminStatus: interfaces.StatusUp, OperStatus: interfaces.StatusDown,
+		ConnectorPresent: interfaces.TruthValueTrue}
+	unplugged := &interfaces.InterfaceDetail{Name: "Gi0/3", AdminStatus: interfaces.StatusUp, OperStatus: interfaces.StatusDown,
+		ConnectorPresent: interfaces.TruthValueFalse}
+	shutdown := &interfaces.InterfaceDetail{Name: "Gi0/4", AdminStatus: interfaces.StatusDown, OperStatus: interfaces.StatusDown,
+		ConnectorPresent: interfaces.TruthValueTrue}
+
+	tests := []struct {
+		name             string
+		deviceInterfaces map[int]*interfaces.InterfaceDetail
+		want             gomonitor.ExitCode
+		wantMessage      string
+	}{
+		{"all up", map[int]*interfaces.InterfaceDetail{1: up}, gomonitor.OK, "0 interface(s) down with no connector"},
+		{"connector present and down", map[int]*interfaces.InterfaceDetail{1: up, 2: fault, 3: unplugged}, gomonitor.Critical,
+			"Connector present but link down on 1 interface(s): Gi0/2 (index 2). 1 interface(s) down with no connector"},
+		{"connector absent and down", map[int]*interfaces.InterfaceDetail{1: up, 3: unplugged}, gomonitor.OK,
+			"1 interface(s) down with no connector"},
+		{"administratively down", map[int]*interfaces.InterfaceDetail{4: shutdown}, gomonitor.OK, "0 interface(s) down with no connector"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := CheckConnectorStatus(tt.deviceInterfaces)
+			if result.ExitCode != tt.want {
+				t.Errorf("CheckConnectorStatus returned %s (%s), want %s", result.ExitCode, result.Message, tt.want)
+			}
+			if !strings.Contains(result.Message, tt.wantMessage) {
+				t.Errorf("CheckConnectorStatus message %q does not contain %q", result.Message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestEvaluateErrorThresholds(t *testing.T) {
+	deviceInterfaces := map[int]*interfaces.InterfaceDetail{
+		1: {Name: "Gi0/1", InErrors: 5, OutErrors: 5, InDiscards: 0, OutDiscards: 0},
+		2: {Name: "Gi0/2", InErrors: 60, OutErrors: 50, InDiscards: 1, OutDiscards: 0},
+	}
+	rates := map[int]interfaces.ErrorRates{
+		1: {Errors: 0.5, Discards: 0},
+	}
+
+	tests := []struct {
+		name        string
+		options     CheckOptions
+		want        gomonitor.ExitCode
+		wantProblem []string
+	}{
+		{"counters below thresholds", CheckOptions{WarnErrors: 200, CritErrors: 300}, gomonitor.OK, nil},
+		{"counters warning", CheckOptions{WarnErrors: 100, CritErrors: 300}, gomonitor.Warning,
+			[]string{"Gi0/2 (index 2) errors: 110 discards: 1"}},
+		{"counters critical", CheckOptions{WarnErrors: 5, CritErrors: 100}, gomonitor.Critical,
+			[]string{"Gi0/1 (index 1) errors: 10 discards: 0", "Gi0/2 (index 2) errors: 110 discards: 1"}},
+		{"counter discards warning", CheckOptions{WarnDiscards: 0.5}, gomonitor.Warning,
+			[]string{"Gi0/2 (index 2) errors: 110 discards: 1"}},
+		{"rates warning", CheckOptions{WarnErrors: 0.1, CritErrors: 1, ErrorRates: true}, gomonitor.Warning,
+			[]string{"Gi0/1 (index 1) errors: 0.50/s discards: 0.00/s"}},
+		{"rates skip interfaces without a rate", CheckOptions{WarnErrors: 1, ErrorRates: true}, gomonitor.OK, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, problems := evaluateErrorThresholds(deviceInterfaces, rates, tt.options)
+			if status != tt.want {
+				t.Errorf("evaluateErrorThresholds returned %s (%v), want %s", status, problems, tt.want)
+			}
+			if strings.Join(problems, ", ") != strings.Join(tt.wantProblem, ", ") {
+				t.Errorf("evaluateErrorThresholds problems = %q, want %q", problems, tt.wantProblem)
+			}
+		})
+	}
+}
+
+// interfaceAgent starts an agent holding Gi0/1, which is up, and Gi0/2, which is down with a connector present
+// and 150 input errors.
+func interfaceAgent(t *testing.T) *snmp.Client {
+	values := make(map[string]interface{})
+	for index, name := range map[int]string{1: "Gi0/1", 2: "Gi0/2"} {
+		oid := func(column string) string { return fmt.Sprintf("%s.%d", column, index) }
+		values[oid(interfaces.OIDIfIndex)] = index
+		values[oid(interfaces.OIDIfName)] = name
+		values[oid(interfaces.OIDIfAdminStatus)] = interfaces.StatusUp
+		values[oid(interfaces.OIDIfOperStatus)] = interfaces.StatusUp
+		values[oid(interfaces.OIDIfConnectorPresent)] = interfaces.TruthValueTrue
+		values[oid(interfaces.OIDIfInErrors)] = uint32(0)
+	}
+	values[interfaces.OIDIfOperStatus+".2"] = interfaces.StatusDown
+	values[interfaces.OIDIfInErrors+".2"] = uint32(150)
+	agent := snmptest.NewAgent(t, values)
+	return &snmp.Client{Target: agent.Target, Community: "public", Timeout: time.Second, Retries: -1}
+}
+
+func TestCheckInterfaceMetricsJSON(t *testing.T) {
+	tests := []struct {
+		name         string
+		options      CheckOptions
+		want         gomonitor.ExitCode
+		wantProblems []string
+	}{
+		{"no problems", CheckOptions{}, gomonitor.OK, []string{}},
+		{"error threshold", CheckOptions{WarnErrors: 100}, gomonitor.Warning,
+			[]string{"Interfaces exceeding error/discard thresholds: Gi0/2 (index 2) errors: 150 discards: 0"}},
+		{"oper status", CheckOptions{CheckOperStatus: true}, gomonitor.Critical, []string{"Interfaces down: Gi0/2 (index 2, alias '') admin up but oper status down"}},
+		{"connector", CheckOptions{CheckConnector: true}, gomonitor.Critical,
+			[]string{"Connector present but link down on 1 interface(s): Gi0/2 (index 2). 0 interface(s) down with no connector"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.options.Output = "json"
+			result := CheckInterfaceMetrics(interfaceAgent(t), tt.options)
+			if result.ExitCode != tt.want {
+				t.Errorf("CheckInterfaceMetrics returned %s (%s), want %s", result.ExitCode, result.Message, tt.want)
+			}
+			var output interfacesOutput
+			if err := json.Unmarshal([]byte(result.Message), &output); err != nil {
+				t.Fatalf("CheckInterfaceMetrics message %q is not the JSON object: %v", result.Message, err)
+			}
+			if fmt.Sprintf("%q", output.Problems) != fmt.Sprintf("%q", tt.wantProblems) {
+				t.Errorf("problems = %q, want %q", output.Problems, tt.wantProblems)
+			}
+			if len(output.Interfaces) != 2 || output.Interfaces[0].Name != "Gi0/1" || output.Interfaces[1].Name != "Gi0/2" {
+				t.Errorf("interfaces = %+v, want Gi0/1 and Gi0/2", output.Interfaces)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/dmabry/gochecks/internal/config"
+	"github.com/dmabry/gochecks/internal/plugin"
+	"github.com/dmabry/gochecks/internal/snmp"
+	"github.com/dmabry/gochecks/internal/threshold"
+	"github.com/dmabry/gomonitor"
+	"strings"
+)
+
+// oidLaLoad is UCD-SNMP-MIB::laLoad, the 1, 5 and 15 minute load averages at indexes 1, 2 and 3.
+const oidLaLoad = ".1.3.6.1.4.1.2021.10.1.3"
+
+// loadNames are the performance data labels of the 1, 5 and 15 minute load averages.
+var loadNames = [3]string{"load1", "load5", "load15"}
+
+// parseLoad converts an laLoad value to a float64. Agents return the load as an OCTET STRING, sometimes
+// padded with whitespace or NUL bytes, so those are trimmed before parsing.
+func parseLoad(value interface{}) (float64, error) {
+	if val, ok := value.([]byte); ok {
+		value = []byte(strings.Trim(string(val), " \t\r\n\x00"))
+	}
+	return snmp.ToFloat64(value)
+}
+
+// GetLoadAverages retrieves the 1, 5 and 15 minute load averages from the SNMP target.
+func GetLoadAverages(snmpClient *snmp.Client) ([3]float64, error) {
+	var loads [3]float64
+	oids := []string{oidLaLoad + ".1", oidLaLoad + ".2", oidLaLoad + ".3"}
+	result, _, err := snmpClient.GetValue(oids)
+	if err != nil {
+		return loads, err
+	}
+	if len(result.Variables) != len(oids) {
+		return loads, fmt.Errorf("expected %d load averages, got %d", len(oids), len(result.Variables))
+	}
+	for i, variable := range result.Variables {
+		load, err := parseLoad(variable.Value)
+		if err != nil {
+			return loads, fmt.Errorf("invalid %s from %s: %w", loadNames[i], variable.Name, err)
+		}
+		loads[i] = load
+	}
+	return loads, nil
+}
+
+// parseRangeTriple parses a comma separated triple of threshold ranges for the 1, 5 and 15 minute load
+// averages, e.g. "4,3,2". An empty spec disables every level, and an empty element disables that level.
+func parseRangeTriple(spec string) ([3]*threshold.Range, error) {
+	var ranges [3]*threshold.Range
+	if spec == "" {
+		return ranges, nil
+	}
+	parts := strings.Split(spec, ",")
+	if len(parts) != 3 {
+		return ranges, fmt.Errorf("expected 3 comma separated ranges, got '%s'", spec)
+	}
+	for i, part := range parts {
+		r, err := threshold.ParseOptional(strings.TrimSpace(part))
+		if err != nil {
+			return ranges, err
+		}
+		ranges[i] = r
+	}
+	return ranges, nil
+}
+
+// DetermineLoad evaluates the 1, 5 and 15 minute load averages against their warn and crit ranges,
+// where a nil range disables that level. If enablePerf is true, the load averages are added as
+// performance data as load1, load5 and load15.
+//
+// Example:
+//
+//	warn, _ := parseRangeTriple("4,3,2")
+//	crit, _ := parseRangeTriple("8,6,4")
+//	result := DetermineLoad([3]float64{0.5, 0.4, 0.3}, warn, crit, true)
+//	result.SendResult()
+func DetermineLoad(loads [3]float64, warn [3]*threshold.Range, crit [3]*threshold.Range, enablePerf bool) *gomonitor.CheckResult {
+	checkResult := gomonitor.NewCheckResult()
+
+	status := gomonitor.OK
+	for i, load := range loads {
+		perfData := gomonitor.PerformanceMetric{Value: load, Min: 0}
+		if warn[i] != nil {
+			perfData.Warn = warn[i].Bound()
+		}
+		if crit[i] != nil {
+			perfData.Crit = crit[i].Bound()
+		}
+		if crit[i] != nil && crit[i].Breaches(load) {
+			status = gomonitor.Critical
+		} else if warn[i] != nil && warn[i].Breaches(load) && status == gomonitor.OK {
+			status = gomonitor.Warning
+		}
+		if enablePerf {
+			checkResult.AddPerformanceData(loadNames[i], perfData)
+		}
+	}
+
+	checkResult.SetResult(status, fmt.Sprintf("Load average: %.2f, %.2f, %.2f", loads[0], loads[1], loads[2]))
+	return checkResult
+}
+
+// main is the entry point of the program. It parses command-line flags, creates an SNMP client,
+// reads the load averages of the target SNMP device and evaluates them using the DetermineLoad function.
+// The result of the check is then sent using the SendResult method.
+func main() {
+	snmpFlags := snmp.Flags{}
+	snmpFlags.Register()
+	warn := flag.String("warn", "", "Warning ranges for the 1, 5 and 15 minute load averages, e.g. 4,3,2. If not provided, there is no warning level.")
+	crit := flag.String("crit", "", "Critical ranges for the 1, 5 and 15 minute load averages, e.g. 8,6,4. If not provided, there is no critical level.")
+	enablePerfData := flag.Bool("enablePerfData", false, "Enable performance data. Default is false.")
+	if err := config.Parse(); err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+
+	warnRanges, err := parseRangeTriple(*warn)
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid warn: %s", err))
+	}
+	critRanges, err := parseRangeTriple(*crit)
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid crit: %s", err))
+	}
+
+	snmpClient, err := snmpFlags.Client()
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+	if snmpFlags.Validate {
+		plugin.Validate(&snmpClient)
+	}
+	loads, err := GetLoadAverages(&snmpClient)
+	if err != nil {
+		eMessage := fmt.Sprintf("SNMP target %s failed to return load averages. %s", snmpClient.Target, err)
+		plugin.Exit(plugin.ErrorStatus(err), eMessage)
+	}
+
+	result := DetermineLoad(loads, warnRanges, critRanges, *enablePerfData)
+	result.SendResult()
+}
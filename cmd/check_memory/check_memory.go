@@ -0,0 +1,204 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/dmabry/gochecks/internal/config"
+	"github.com/dmabry/gochecks/internal/plugin"
+	"github.com/dmabry/gochecks/internal/snmp"
+	"github.com/dmabry/gochecks/internal/storage"
+	"github.com/dmabry/gochecks/internal/threshold"
+	"github.com/dmabry/gomonitor"
+)
+
+// The UCD-SNMP-MIB memory objects, all in KB. memBuffer and memCached are reclaimable and not counted as used.
+const (
+	oidMemTotalSwap = ".1.3.6.1.4.1.2021.4.3.0"
+	oidMemAvailSwap = ".1.3.6.1.4.1.2021.4.4.0"
+	oidMemTotalReal = ".1.3.6.1.4.1.2021.4.5.0"
+	oidMemAvailReal = ".1.3.6.1.4.1.2021.4.6.0"
+	oidMemBuffer    = ".1.3.6.1.4.1.2021.4.14.0"
+	oidMemCached    = ".1.3.6.1.4.1.2021.4.15.0"
+)
+
+// MemoryMetrics holds the RAM and swap usage of a device in KB. SwapTotal is 0 when swap is not
+// configured or not reported by the source.
+type MemoryMetrics struct {
+	RAMTotal  uint64
+	RAMUsed   uint64
+	SwapTotal uint64
+	SwapUsed  uint64
+}
+
+// usedPercent returns used in percent of total, or 0 when total is 0.
+func usedPercent(used uint64, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(used) * 100 / float64(total)
+}
+
+// GetUCDMemoryMetrics retrieves the RAM and swap usage from the UCD-SNMP-MIB memory objects.
+// Buffers and cache are excluded from the used RAM. It returns an error if the target does not
+// implement memTotalReal and memAvailReal.
+func GetUCDMemoryMetrics(snmpClient *snmp.Client) (*MemoryMetrics, error) {
+	oids := []string{oidMemTotalSwap, oidMemAvailSwap, oidMemTotalReal, oidMemAvailReal, oidMemBuffer, oidMemCached}
+	result, _, err := snmpClient.GetValue(oids)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]uint64)
+	for _, variable := range result.Variables {
+		if value, err := snmp.ToFloat64(variable.Value); err == nil && value > 0 {
+			values[variable.Name] = uint64(value)
+		}
+	}
+	if _, ok := values[oidMemTotalReal]; !ok {
+		return nil, fmt.Errorf("memTotalReal is not available, the target may not implement UCD-SNMP-MIB")
+	}
+
+	free := values[oidMemAvailReal] + values[oidMemBuffer] + values[oidMemCached]
+	metrics := &MemoryMetrics{
+		RAMTotal:  values[oidMemTotalReal],
+		SwapTotal: values[oidMemTotalSwap],
+	}
+	if free < metrics.RAMTotal {
+		metrics.RAMUsed = metrics.RAMTotal - free
+	}
+	if values[oidMemAvailSwap] < metrics.SwapTotal {
+		metrics.SwapUsed = metrics.SwapTotal - values[oidMemAvailSwap]
+	}
+	return metrics, nil
+}
+
+// GetHostResourcesMemoryMetrics retrieves the RAM usage from the hrStorageTable row of type hrStorageRam,
+// for devices without UCD-SNMP-MIB. Swap is not reported. It returns an error if there is no RAM row.
+func GetHostResourcesMemoryMetrics(snmpClient *snmp.Client) (*MemoryMetrics, error) {
+	units, err := storage.Collect(snmpClient)
+	if err != nil {
+		return nil, err
+	}
+	for _, unit := range units {
+		if unit.Type == storage.OIDHrStorageRam {
+			return &MemoryMetrics{
+				RAMTotal: unit.SizeBytes() / 1024,
+				RAMUsed:  unit.UsedBytes() / 1024,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("hrStorageTable has no hrStorageRam entry")
+}
+
+// evaluate compares percent against the warn and crit ranges, where a nil range disables that level.
+func evaluate(percent float64, warn *threshold.Range, crit *threshold.Range) gomonitor.ExitCode {
+	if crit != nil && crit.Breaches(percent) {
+		return gomonitor.Critical
+	}
+	if warn != nil && warn.Breaches(percent) {
+		return gomonitor.Warning
+	}
+	return gomonitor.OK
+}
+
+// DetermineMemoryUsage evaluates the RAM and swap usage in percent against their warn and crit ranges,
+// where a nil range disables that level. The swap ranges are ignored when no swap is reported.
+// If enablePerf is true, the used RAM and swap in KB are added as performance data with the totals as maximum.
+//
+// Example:
+//
+//	warn, _ := threshold.Parse("80")
+//	crit, _ := threshold.Parse("90")
+//	result := DetermineMemoryUsage(*metrics, &warn, &crit, nil, nil, true)
+//	result.SendResult()
+func DetermineMemoryUsage(metrics MemoryMetrics, warnPct *threshold.Range, critPct *threshold.Range, warnSwapPct *threshold.Range, critSwapPct *threshold.Range, enablePerf bool) *gomonitor.CheckResult {
+	checkResult := gomonitor.NewCheckResult()
+
+	ramPct := usedPercent(metrics.RAMUsed, metrics.RAMTotal)
+	message := fmt.Sprintf("RAM used %.2f%% (%d of %d KB)", ramPct, metrics.RAMUsed, metrics.RAMTotal)
+	ramStatus := evaluate(ramPct, warnPct, critPct)
+	status := ramStatus
+	if metrics.SwapTotal > 0 {
+		swapPct := usedPercent(metrics.SwapUsed, metrics.SwapTotal)
+		message += fmt.Sprintf(", swap used %.2f%% (%d of %d KB)", swapPct, metrics.SwapUsed, metrics.SwapTotal)
+		swapStatus := evaluate(swapPct, warnSwapPct, critSwapPct)
+		status = plugin.WorseStatus(ramStatus, swapStatus)
+	}
+	checkResult.SetResult(status, message)
+
+	if enablePerf {
+		checkResult.AddPerformanceData("ram_used", gomonitor.PerformanceMetric{Value: float64(metrics.RAMUsed), Min: 0, Max: float64(metrics.RAMTotal), UnitOM: "KB"})
+		if metrics.SwapTotal > 0 {
+			checkResult.AddPerformanceData("swap_used", gomonitor.PerformanceMetric{Value: float64(metrics.SwapUsed), Min: 0, Max: float64(metrics.SwapTotal), UnitOM: "KB"})
+		}
+	}
+	return checkResult
+}
+
+// main is the entry point of the program. It parses command-line flags, creates an SNMP client,
+// and retrieves the memory usage of the target SNMP device from the selected source before
+// evaluating it using the DetermineMemoryUsage function.
+// The result of the check is then sent using the SendResult method.
+func main() {
+	snmpFlags := snmp.Flags{}
+	snmpFlags.Register()
+	source := flag.String("source", "ucd", "The MIB to read memory usage from: ucd (UCD-SNMP-MIB) or hostresources (hrStorageTable).")
+	warnPct := flag.String("warnPct", "", "Warning range for the RAM used in percent, e.g. 80. If not provided, there is no warning level.")
+	critPct := flag.String("critPct", "", "Critical range for the RAM used in percent, e.g. 90. If not provided, there is no critical level.")
+	warnSwapPct := flag.String("warnSwapPct", "", "Warning range for the swap used in percent, e.g. 50. If not provided, there is no warning level.")
+	critSwapPct := flag.String("critSwapPct", "", "Critical range for the swap used in percent, e.g. 80. If not provided, there is no critical level.")
+	enablePerfData := flag.Bool("enablePerfData", false, "Enable performance data. Default is false.")
+	if err := config.Parse(); err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+
+	thresholds := make(map[string]*threshold.Range)
+	for name, spec := range map[string]string{"warnPct": *warnPct, "critPct": *critPct, "warnSwapPct": *warnSwapPct, "critSwapPct": *critSwapPct} {
+		r, err := threshold.ParseOptional(spec)
+		if err != nil {
+			plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid %s: %s", name, err))
+		}
+		thresholds[name] = r
+	}
+
+	snmpClient, err := snmpFlags.Client()
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+	if snmpFlags.Validate {
+		plugin.Validate(&snmpClient)
+	}
+
+	var metrics *MemoryMetrics
+	switch *source {
+	case "ucd":
+		metrics, err = GetUCDMemoryMetrics(&snmpClient)
+	case "hostresources":
+		metrics, err = GetHostResourcesMemoryMetrics(&snmpClient)
+	default:
+		plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid source '%s', expected ucd or hostresources", *source))
+	}
+	if err != nil {
+		eMessage := fmt.Sprintf("SNMP target %s failed to return memory usage. %s", snmpClient.Target, err)
+		plugin.Exit(plugin.ErrorStatus(err), eMessage)
+	}
+
+	result := DetermineMemoryUsage(*metrics, thresholds["warnPct"], thresholds["critPct"], thresholds["warnSwapPct"], thresholds["critSwapPct"], *enablePerfData)
+	result.SendResult()
+}
@@ -0,0 +1,162 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/dmabry/gochecks/internal/config"
+	"github.com/dmabry/gochecks/internal/plugin"
+	"github.com/dmabry/gochecks/internal/snmp"
+	"github.com/dmabry/gomonitor"
+)
+
+// compare evaluates the relationship "left op right" for the supported operators.
+// It returns an error when the operator is not one of ==, !=, <, >, <= or >=.
+func compare(left float64, op string, right float64) (bool, error) {
+	switch op {
+	case "==":
+		return left == right, nil
+	case "!=":
+		return left != right, nil
+	case "<":
+		return left < right, nil
+	case ">":
+		return left > right, nil
+	case "<=":
+		return left <= right, nil
+	case ">=":
+		return left >= right, nil
+	default:
+		return false, fmt.Errorf("unsupported operator '%s'", op)
+	}
+}
+
+// EvaluateRelationship asserts that the relationship "first op second" holds between two SNMP values.
+// Both values are coerced to numbers using snmp.ToFloat64. When either value is not numeric, the
+// equality operators (== and !=) fall back to comparing the values as strings, while the ordering
+// operators return an Unknown result since there is no meaningful comparison.
+// A violated relationship returns a Critical result, otherwise OK.
+//
+// Example:
+//
+//	result := EvaluateRelationship(".1.3.6.1.2.1.15.3.1.3.192.0.2.1", 6, "==", ".1.3.6.1.2.1.15.3.1.2.192.0.2.1", 6)
+//	result.SendResult()
+func EvaluateRelationship(firstOID string, first interface{}, op string, secondOID string, second interface{}) *gomonitor.CheckResult {
+	checkResult := gomonitor.NewCheckResult()
+
+	var holds bool
+	var err error
+	var message string
+	firstNumber, err1 := snmp.ToFloat64(first)
+	secondNumber, err2 := snmp.ToFloat64(second)
+	if err1 == nil && err2 == nil {
+		holds, err = compare(firstNumber, op, secondNumber)
+		message = fmt.Sprintf("%s (%g) %s %s (%g)", firstOID, firstNumber, op, secondOID, secondNumber)
+	} else {
+		firstString := snmp.ToString(first)
+		secondString := snmp.ToString(second)
+		switch op {
+		case "==":
+			holds = firstString == secondString
+		case "!=":
+			holds = firstString != secondString
+		default:
+			eMessage := fmt.Sprintf("Cannot compare %s (%T) %s %s (%T): values must be numeric", firstOID, first, op, secondOID, second)
+			checkResult.SetResult(gomonitor.Unknown, eMessage)
+			return checkResult
+		}
+		message = fmt.Sprintf("%s (%s) %s %s (%s)", firstOID, firstString, op, secondOID, secondString)
+	}
+	if err != nil {
+		checkResult.SetResult(gomonitor.Unknown, err.Error())
+		return checkResult
+	}
+
+	if !holds {
+		checkResult.SetResult(gomonitor.Critical, "Relationship violated: "+message)
+		return checkResult
+	}
+	checkResult.SetResult(gomonitor.OK, message)
+	return checkResult
+}
+
+// CheckOIDRelationship fetches two OIDs from the SNMP target in a single request and asserts the
+// relationship between their values using EvaluateRelationship.
+// If the target times out, an Unknown result is returned. If the request otherwise fails or either OID is missing,
+// a Critical result is returned.
+// If enablePerfData is true, both values (when numeric) and the SNMP latency are added as performance data.
+func CheckOIDRelationship(snmpClient *snmp.Client, firstOID string, op string, secondOID string, enablePerfData bool) *gomonitor.CheckResult {
+	result, latency, err := snmpClient.GetValue([]string{firstOID, secondOID})
+	if err != nil {
+		checkResult := gomonitor.NewCheckResult()
+		eMessage := fmt.Sprintf("SNMP target %s failed to return data for requested OIDs. %s", snmpClient.Target, err)
+		checkResult.SetResult(plugin.ErrorStatus(err), eMessage)
+		return checkResult
+	}
+
+	if len(result.Variables) < 2 || snmp.VariableError(result.Variables[0]) != nil || snmp.VariableError(result.Variables[1]) != nil ||
+		result.Variables[0].Value == nil || result.Variables[1].Value == nil {
+		checkResult := gomonitor.NewCheckResult()
+		eMessage := fmt.Sprintf("SNMP target %s returned no value for %s or %s", snmpClient.Target, firstOID, secondOID)
+		checkResult.SetResult(gomonitor.Critical, eMessage)
+		return checkResult
+	}
+
+	first := result.Variables[0].Value
+	second := result.Variables[1].Value
+	checkResult := EvaluateRelationship(firstOID, first, op, secondOID, second)
+	if enablePerfData {
+		if value, err := snmp.ToFloat64(first); err == nil {
+			checkResult.AddPerformanceData("first", gomonitor.PerformanceMetric{Value: value})
+		}
+		if value, err := snmp.ToFloat64(second); err == nil {
+			checkResult.AddPerformanceData("second", gomonitor.PerformanceMetric{Value: value})
+		}
+		checkResult.AddPerformanceData("latency", gomonitor.PerformanceMetric{Value: latency.Seconds(), UnitOM: "s"})
+	}
+	return checkResult
+}
+
+// main is the entry point of the program. It parses command-line flags, creates an SNMP client,
+// and performs a check on the target SNMP device using the CheckOIDRelationship function.
+// The result of the check is then sent using the SendResult method.
+func main() {
+	snmpFlags := snmp.Flags{}
+	snmpFlags.Register()
+	firstOID := flag.String("oid1", "", "The first OID to compare.")
+	secondOID := flag.String("oid2", "", "The second OID to compare.")
+	op := flag.String("op", "==", "The relationship expected between oid1 and oid2: ==, !=, <, >, <= or >=.")
+	enablePerfData := flag.Bool("enablePerfData", false, "Enable performance data. Default is false.")
+	if err := config.Parse(); err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+
+	if *firstOID == "" || *secondOID == "" {
+		plugin.Exit(gomonitor.Unknown, "Both -oid1 and -oid2 are required")
+	}
+
+	snmpClient, err := snmpFlags.Client()
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+	if snmpFlags.Validate {
+		plugin.Validate(&snmpClient)
+	}
+	result := CheckOIDRelationship(&snmpClient, *firstOID, *op, *secondOID, *enablePerfData)
+	result.SendResult()
+}
@@ -0,0 +1,137 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/dmabry/gochecks/internal/snmp"
+	"github.com/dmabry/gochecks/internal/snmp/snmptest"
+	"github.com/dmabry/gomonitor"
+	"testing"
+	"time"
+)
+
+const (
+	testFirstOID  = ".1.3.6.1.4.1.99999.1.1"
+	testSecondOID = ".1.3.6.1.4.1.99999.1.2"
+)
+
+func TestCheckOIDRelationship(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+		op     string
+		want   gomonitor.ExitCode
+	}{
+		{"holds", map[string]interface{}{testFirstOID: 6, testSecondOID: 6}, "==", gomonitor.OK},
+		{"violated", map[string]interface{}{testFirstOID: 3, testSecondOID: 6}, "==", gomonitor.Critical},
+		{"first missing", map[string]interface{}{testSecondOID: 6}, "==", gomonitor.Critical},
+		{"second missing", map[string]interface{}{testFirstOID: 6}, "<", gomonitor.Critical},
+		{"both missing", nil, "!=", gomonitor.Critical},
+		{"strings", map[string]interface{}{testFirstOID: "up", testSecondOID: "up"}, "==", gomonitor.OK},
+		{"strings ordered", map[string]interface{}{testFirstOID: "up", testSecondOID: "down"}, "<", gomonitor.Unknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent := snmptest.NewAgent(t, tt.values)
+			client := snmp.Client{Target: agent.Target, Community: "public", Timeout: time.Second, Retries: -1}
+			result := CheckOIDRelationship(&client, testFirstOID, tt.op, testSecondOID, true)
+			if result.ExitCode != tt.want {
+				t.Errorf("CheckOIDRelationship returned %s (%s), want %s", result.ExitCode, result.Message, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckOIDRelationshipTimeout(t *testing.T) {
+	agent := snmptest.NewAgent(t, map[string]interface{}{testFirstOID: 6, testSecondOID: 6})
+	agent.Drop(1)
+	client := snmp.Client{Target: agent.Target, Community: "public", Timeout: 100 * time.Millisecond, Retries: -1}
+	result := CheckOIDRelationship(&client, testFirstOID, "==", testSecondOID, false)
+	if result.ExitCode != gomonitor.Unknown {
+		t.Errorf("CheckOIDRelationship returned %s (%s) on a timeout, want Unknown", result.ExitCode, result.Message)
+	}
+}
+
+func TestEvaluateRelationshipNil(t *testing.T) {
+	result := EvaluateRelationship(testFirstOID, nil, "<", testSecondOID, 6)
+	if result.ExitCode != gomonitor.Unknown {
+		t.Errorf("EvaluateRelationship returned %s (%s) for a nil value, want Unknown", result.ExitCode, result.Message)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		left  float64
+		op    string
+		right float64
+		want  bool
+	}{
+		{1, "==", 1, true},
+		{1, "==", 2, false},
+		{1, "!=", 2, true},
+		{1, "!=", 1, false},
+		{1, "<", 2, true},
+		{2, "<", 2, false},
+		{3, ">", 2, true},
+		{2, ">", 2, false},
+		{2, "<=", 2, true},
+		{3, "<=", 2, false},
+		{2, ">=", 2, true},
+		{1, ">=", 2, false},
+	}
+	for _, tt := range tests {
+		holds, err := compare(tt.left, tt.op, tt.right)
+		if err != nil {
+			t.Errorf("compare(%g %s %g) returned error: %v", tt.left, tt.op, tt.right, err)
+			continue
+		}
+		if holds != tt.want {
+			t.Errorf("compare(%g %s %g) = %t, want %t", tt.left, tt.op, tt.right, holds, tt.want)
+		}
+	}
+	if _, err := compare(1, "=~", 1); err == nil {
+		t.Error("compare returned no error for an unsupported operator")
+	}
+}
+
+func TestEvaluateRelationship(t *testing.T) {
+	tests := []struct {
+		name   string
+		first  interface{}
+		op     string
+		second interface{}
+		want   gomonitor.ExitCode
+	}{
+		{"integers equal", 6, "==", 6, gomonitor.OK},
+		{"mixed numeric types", uint32(6), "==", uint64(6), gomonitor.OK},
+		{"numeric string", []byte(" 42 "), ">", 41, gomonitor.OK},
+		{"ordering violated", uint(5), ">=", 6, gomonitor.Critical},
+		{"strings equal", []byte("up"), "==", []byte("up"), gomonitor.OK},
+		{"strings differ", []byte("up"), "!=", []byte("down"), gomonitor.OK},
+		{"string against number", []byte("up"), "==", 6, gomonitor.Critical},
+		{"string ordered against number", []byte("up"), ">", 6, gomonitor.Unknown},
+		{"unsupported operator", 6, "=~", 6, gomonitor.Unknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := EvaluateRelationship(testFirstOID, tt.first, tt.op, testSecondOID, tt.second)
+			if result.ExitCode != tt.want {
+				t.Errorf("EvaluateRelationship returned %s (%s), want %s", result.ExitCode, result.Message, tt.want)
+			}
+		})
+	}
+}
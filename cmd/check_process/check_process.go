@@ -0,0 +1,160 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/dmabry/gochecks/internal/config"
+	"github.com/dmabry/gochecks/internal/plugin"
+	"github.com/dmabry/gochecks/internal/snmp"
+	"github.com/dmabry/gochecks/internal/threshold"
+	"github.com/dmabry/gomonitor"
+	"regexp"
+)
+
+// The HOST-RESOURCES-MIB hrSWRunName and hrSWRunStatus columns of the hrSWRunTable, indexed by hrSWRunIndex.
+// hrSWRunStatus is running(1), runnable(2), notRunnable(3) or invalid(4).
+const (
+	oidHrSWRunName       = ".1.3.6.1.2.1.25.4.2.1.2"
+	oidHrSWRunStatus     = ".1.3.6.1.2.1.25.4.2.1.7"
+	hrSWRunStatusInvalid = 4
+)
+
+// CountProcesses walks hrSWRunName on the SNMP target and counts the processes whose name matches pattern.
+// When skipInvalid is set, hrSWRunStatus is walked as well and invalid processes, which the agent is about
+// to remove from the table, are not counted.
+func CountProcesses(snmpClient *snmp.Client, pattern *regexp.Regexp, skipInvalid bool) (int, error) {
+	return snmp.WithSession(snmpClient, func(session *snmp.Session) (int, error) {
+		return countProcesses(session, pattern, skipInvalid)
+	})
+}
+
+// countProcesses walks the hrSWRunTable over session for CountProcesses.
+func countProcesses(session *snmp.Session, pattern *regexp.Regexp, skipInvalid bool) (int, error) {
+	names, _, err := session.Walk(oidHrSWRunName)
+	if err != nil {
+		return 0, err
+	}
+	var statuses map[string]interface{}
+	if skipInvalid {
+		statuses, _, err = session.Walk(oidHrSWRunStatus)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	count := 0
+	for oid, value := range names {
+		if !pattern.MatchString(snmp.ToString(value)) {
+			continue
+		}
+		if skipInvalid {
+			index, err := snmp.TableIndex(oidHrSWRunName, oid)
+			if err != nil {
+				return 0, err
+			}
+			status, err := snmp.ToFloat64(statuses[oidHrSWRunStatus+"."+index])
+			if err == nil && status == hrSWRunStatusInvalid {
+				continue
+			}
+		}
+		count++
+	}
+	return count, nil
+}
+
+// DetermineProcessCount evaluates the number of matching processes against the warn and crit ranges, where a
+// nil range disables that level. If enablePerf is true, the count is added as performance data.
+//
+// Example:
+//
+//	crit, _ := threshold.Parse("1:")
+//	result := DetermineProcessCount("sshd", 0, nil, &crit, true)
+//	result.SendResult()
+func DetermineProcessCount(name string, count int, warn *threshold.Range, crit *threshold.Range, enablePerf bool) *gomonitor.CheckResult {
+	checkResult := gomonitor.NewCheckResult()
+
+	perfData := gomonitor.PerformanceMetric{Value: float64(count), Min: 0}
+	status := gomonitor.OK
+	if warn != nil {
+		perfData.Warn = warn.Bound()
+		if warn.Breaches(float64(count)) {
+			status = gomonitor.Warning
+		}
+	}
+	if crit != nil {
+		perfData.Crit = crit.Bound()
+		if crit.Breaches(float64(count)) {
+			status = gomonitor.Critical
+		}
+	}
+	checkResult.SetResult(status, fmt.Sprintf("%d process(es) matching '%s'", count, name))
+
+	if enablePerf {
+		checkResult.AddPerformanceData("procs", perfData)
+	}
+	return checkResult
+}
+
+// main is the entry point of the program. It parses command-line flags, creates an SNMP client,
+// counts the matching processes on the target SNMP device and evaluates the count using the
+// DetermineProcessCount function. The result of the check is then sent using the SendResult method.
+func main() {
+	snmpFlags := snmp.Flags{}
+	snmpFlags.Register()
+	name := flag.String("name", "", "Regex the process name must match, e.g. ^sshd$.")
+	warnCount := flag.String("warnCount", "", "Warning range for the number of matching processes, e.g. 1:10. If not provided, there is no warning level.")
+	critCount := flag.String("critCount", "1:", "Critical range for the number of matching processes. Default is 1:, critical when no process matches.")
+	skipInvalid := flag.Bool("skipInvalid", false, "Do not count processes whose hrSWRunStatus is invalid. Default is false.")
+	enablePerfData := flag.Bool("enablePerfData", false, "Enable performance data. Default is false.")
+	if err := config.Parse(); err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+
+	if *name == "" {
+		plugin.Exit(gomonitor.Unknown, "-name is required")
+	}
+	pattern, err := regexp.Compile(*name)
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid name '%s': %s", *name, err))
+	}
+	warnRange, err := threshold.ParseOptional(*warnCount)
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid warnCount: %s", err))
+	}
+	critRange, err := threshold.ParseOptional(*critCount)
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid critCount: %s", err))
+	}
+
+	snmpClient, err := snmpFlags.Client()
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+	if snmpFlags.Validate {
+		plugin.Validate(&snmpClient)
+	}
+	count, err := CountProcesses(&snmpClient, pattern, *skipInvalid)
+	if err != nil {
+		eMessage := fmt.Sprintf("SNMP target %s failed to return the process table. %s", snmpClient.Target, err)
+		plugin.Exit(plugin.ErrorStatus(err), eMessage)
+	}
+
+	result := DetermineProcessCount(*name, count, warnRange, critRange, *enablePerfData)
+	result.SendResult()
+}
@@ -0,0 +1,192 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/dmabry/gochecks/internal/config"
+	"github.com/dmabry/gochecks/internal/plugin"
+	"github.com/dmabry/gochecks/internal/snmp"
+	"github.com/dmabry/gochecks/internal/threshold"
+	"github.com/dmabry/gomonitor"
+	"regexp"
+)
+
+// checkType verifies that an SNMP value has the expected type: "int" for INTEGER, "uint" for
+// Counter32, Counter64, Gauge32 and TimeTicks, "gauge" for any numeric value and "string" for any value.
+func checkType(value interface{}, valueType string) error {
+	switch valueType {
+	case "int":
+		if _, ok := value.(int); !ok {
+			return fmt.Errorf("value is not of type int: %T -> %v", value, value)
+		}
+	case "uint":
+		switch value.(type) {
+		case uint, uint32, uint64:
+		default:
+			return fmt.Errorf("value is not of type uint: %T -> %v", value, value)
+		}
+	case "gauge":
+		if _, err := snmp.ToFloat64(value); err != nil {
+			return err
+		}
+	case "string":
+	default:
+		return fmt.Errorf("unsupported type '%s', expected int, uint, gauge or string", valueType)
+	}
+	return nil
+}
+
+// EvaluateValue evaluates a single SNMP value against the check thresholds.
+// Numeric types are compared against the warn and crit ranges in the Nagios threshold format parsed by
+// threshold.Parse, where an empty range disables the level. The string type matches the value against
+// pattern and returns Critical when it does not match.
+// If enablePerfData is true, numeric values are added as performance data under label.
+//
+// Example:
+//
+//	result := EvaluateValue(".1.3.6.1.4.1.2021.11.11.0", 85, "int", "20:", "10:", nil, "idle", true)
+//	result.SendResult()
+func EvaluateValue(oid string, value interface{}, valueType string, warn string, crit string, pattern *regexp.Regexp, label string, enablePerfData bool) *gomonitor.CheckResult {
+	checkResult := gomonitor.NewCheckResult()
+
+	if err := checkType(value, valueType); err != nil {
+		checkResult.SetResult(gomonitor.Unknown, fmt.Sprintf("%s: %s", oid, err))
+		return checkResult
+	}
+
+	if valueType == "string" {
+		text := snmp.ToString(value)
+		if pattern != nil && !pattern.MatchString(text) {
+			checkResult.SetResult(gomonitor.Critical, fmt.Sprintf("%s = '%s' does not match '%s'", oid, text, pattern))
+			return checkResult
+		}
+		checkResult.SetResult(gomonitor.OK, fmt.Sprintf("%s = '%s'", oid, text))
+		return checkResult
+	}
+
+	number, err := snmp.ToFloat64(value)
+	if err != nil {
+		checkResult.SetResult(gomonitor.Unknown, fmt.Sprintf("%s: %s", oid, err))
+		return checkResult
+	}
+
+	perfData := gomonitor.PerformanceMetric{Value: number}
+	status := gomonitor.OK
+	if warn != "" {
+		warnRange, err := threshold.Parse(warn)
+		if err != nil {
+			checkResult.SetResult(gomonitor.Unknown, err.Error())
+			return checkResult
+		}
+		perfData.Warn = warnRange.Bound()
+		if warnRange.Breaches(number) {
+			status = gomonitor.Warning
+		}
+	}
+	if crit != "" {
+		critRange, err := threshold.Parse(crit)
+		if err != nil {
+			checkResult.SetResult(gomonitor.Unknown, err.Error())
+			return checkResult
+		}
+		perfData.Crit = critRange.Bound()
+		if critRange.Breaches(number) {
+			status = gomonitor.Critical
+		}
+	}
+
+	checkResult.SetResult(status, fmt.Sprintf("%s = %g", oid, number))
+	if enablePerfData {
+		checkResult.AddPerformanceData(label, perfData)
+	}
+	return checkResult
+}
+
+// CheckSNMPValue fetches a single OID from the SNMP target and evaluates it using EvaluateValue.
+// If the target times out, an Unknown result is returned. If the request otherwise fails or the OID is missing,
+// a Critical result is returned.
+// If enablePerfData is true, the SNMP latency is added as performance data as well.
+func CheckSNMPValue(snmpClient *snmp.Client, oid string, valueType string, warn string, crit string, pattern *regexp.Regexp, label string, enablePerfData bool) *gomonitor.CheckResult {
+	result, latency, err := snmpClient.GetValue([]string{oid})
+	if err != nil {
+		checkResult := gomonitor.NewCheckResult()
+		eMessage := fmt.Sprintf("SNMP target %s failed to return data for requested OID. %s", snmpClient.Target, err)
+		checkResult.SetResult(plugin.ErrorStatus(err), eMessage)
+		return checkResult
+	}
+	if err := snmp.VariableError(result.Variables[0]); err != nil {
+		checkResult := gomonitor.NewCheckResult()
+		checkResult.SetResult(gomonitor.Critical, fmt.Sprintf("SNMP target %s does not implement %s. %s", snmpClient.Target, oid, err))
+		return checkResult
+	}
+
+	value := result.Variables[0].Value
+	if value == nil {
+		checkResult := gomonitor.NewCheckResult()
+		checkResult.SetResult(gomonitor.Critical, fmt.Sprintf("SNMP target %s returned no value for %s", snmpClient.Target, oid))
+		return checkResult
+	}
+
+	checkResult := EvaluateValue(oid, value, valueType, warn, crit, pattern, label, enablePerfData)
+	if enablePerfData {
+		checkResult.AddPerformanceData("latency", gomonitor.PerformanceMetric{Value: latency.Seconds(), UnitOM: "s"})
+	}
+	return checkResult
+}
+
+// main is the entry point of the program. It parses command-line flags, creates an SNMP client,
+// and performs a check on the target SNMP device using the CheckSNMPValue function.
+// The result of the check is then sent using the SendResult method.
+func main() {
+	snmpFlags := snmp.Flags{}
+	snmpFlags.Register()
+	oid := flag.String("oid", "", "The OID to check.")
+	valueType := flag.String("type", "gauge", "The expected value type: int, uint, gauge or string.")
+	warn := flag.String("warn", "", "Warning threshold range for numeric types, e.g. 80, 10:, ~:100 or @10:20. If not provided, there is no warning level.")
+	crit := flag.String("crit", "", "Critical threshold range for numeric types, e.g. 90, 5:, ~:200 or @10:20. If not provided, there is no critical level.")
+	match := flag.String("regexp", "", "Regex the value must match for the string type. If not provided, any value is OK.")
+	label := flag.String("label", "value", "The performance data label for the value.")
+	enablePerfData := flag.Bool("enablePerfData", false, "Enable performance data. Default is false.")
+	if err := config.Parse(); err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+
+	if *oid == "" {
+		plugin.Exit(gomonitor.Unknown, "-oid is required")
+	}
+
+	var pattern *regexp.Regexp
+	if *match != "" {
+		var err error
+		pattern, err = regexp.Compile(*match)
+		if err != nil {
+			plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid regexp '%s': %s", *match, err))
+		}
+	}
+
+	snmpClient, err := snmpFlags.Client()
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+	if snmpFlags.Validate {
+		plugin.Validate(&snmpClient)
+	}
+	result := CheckSNMPValue(&snmpClient, *oid, *valueType, *warn, *crit, pattern, *label, *enablePerfData)
+	result.SendResult()
+}
@@ -0,0 +1,101 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/dmabry/gochecks/internal/config"
+	"github.com/dmabry/gochecks/internal/plugin"
+	"github.com/dmabry/gochecks/internal/snmp"
+	"github.com/dmabry/gochecks/internal/threshold"
+	"github.com/dmabry/gomonitor"
+)
+
+// CheckLatency measures the round-trip time of a sysUpTime GET to the SNMP target and evaluates it in seconds
+// against the warn and crit ranges, where a nil range disables that level. A slow agent is an early warning of
+// an overloaded management plane. The measured latency is always added as performance data in seconds, along
+// with the configured warn and crit.
+//
+// Example:
+//
+//	warn, _ := threshold.Parse("0.5")
+//	crit, _ := threshold.Parse("2")
+//	result := CheckLatency(&snmpClient, &warn, &crit)
+//	result.SendResult()
+func CheckLatency(snmpClient *snmp.Client, warn *threshold.Range, crit *threshold.Range) *gomonitor.CheckResult {
+	checkResult := gomonitor.NewCheckResult()
+
+	latency, err := snmpClient.Ping()
+	if err != nil {
+		eMessage := fmt.Sprintf("SNMP target %s failed to respond to sysUpTime. %s", snmpClient.Target, err)
+		checkResult.SetResult(plugin.ErrorStatus(err), eMessage)
+		return checkResult
+	}
+
+	seconds := latency.Seconds()
+	latencyPerf := gomonitor.PerformanceMetric{Value: seconds, Min: 0, UnitOM: "s"}
+	status := gomonitor.OK
+	if warn != nil {
+		latencyPerf.Warn = warn.Bound()
+		if warn.Breaches(seconds) {
+			status = gomonitor.Warning
+		}
+	}
+	if crit != nil {
+		latencyPerf.Crit = crit.Bound()
+		if crit.Breaches(seconds) {
+			status = gomonitor.Critical
+		}
+	}
+
+	checkResult.SetResult(status, fmt.Sprintf("SNMP response time %.3fs", seconds))
+	checkResult.AddPerformanceData("latency", latencyPerf)
+	return checkResult
+}
+
+// main is the entry point of the program. It parses command-line flags, creates an SNMP client,
+// and checks the response time of the target SNMP device using the CheckLatency function.
+// The result of the check is then sent using the SendResult method.
+func main() {
+	snmpFlags := snmp.Flags{}
+	snmpFlags.Register()
+	warn := flag.String("warn", "", "Warning range for the response time in seconds, e.g. 0.5. If not provided, there is no warning level.")
+	crit := flag.String("crit", "", "Critical range for the response time in seconds, e.g. 2. If not provided, there is no critical level.")
+	if err := config.Parse(); err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+
+	warnRange, err := threshold.ParseOptional(*warn)
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid warn: %s", err))
+	}
+	critRange, err := threshold.ParseOptional(*crit)
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid crit: %s", err))
+	}
+
+	snmpClient, err := snmpFlags.Client()
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+	if snmpFlags.Validate {
+		plugin.Validate(&snmpClient)
+	}
+	result := CheckLatency(&snmpClient, warnRange, critRange)
+	result.SendResult()
+}
@@ -19,58 +19,135 @@ package main
 import (
 	"flag"
 	"fmt"
+	"github.com/dmabry/gochecks/internal/config"
+	"github.com/dmabry/gochecks/internal/plugin"
 	"github.com/dmabry/gochecks/internal/snmp"
 	"github.com/dmabry/gomonitor"
 	"regexp"
+	"strings"
 )
 
+// oidSysDescr is SNMPv2-MIB::sysDescr.0, the OID checked by default.
+const oidSysDescr = "1.3.6.1.2.1.1.1.0"
+
+// systemOIDNames maps the SNMPv2-MIB system group scalars to their names, used to label the check output.
+var systemOIDNames = map[string]string{
+	"1.3.6.1.2.1.1.1.0": "sysDescr",
+	"1.3.6.1.2.1.1.2.0": "sysObjectID",
+	"1.3.6.1.2.1.1.4.0": "sysContact",
+	"1.3.6.1.2.1.1.5.0": "sysName",
+	"1.3.6.1.2.1.1.6.0": "sysLocation",
+}
+
+// oidLabel returns the name of a system group OID, or the OID itself when it has no known name.
+func oidLabel(oid string) string {
+	if name, ok := systemOIDNames[strings.TrimPrefix(oid, ".")]; ok {
+		return name
+	}
+	return oid
+}
+
+// namedCaptures formats the named capture groups of pattern matched in submatches as "name=value" pairs,
+// e.g. "ver=15.2(4)" for the pattern `Version (?P<ver>[\d.()]+)`. It returns an empty string when the pattern
+// has no named groups or did not match.
+func namedCaptures(pattern *regexp.Regexp, submatches []string) string {
+	var captures []string
+	for i, name := range pattern.SubexpNames() {
+		if name == "" || i >= len(submatches) {
+			continue
+		}
+		captures = append(captures, name+"="+submatches[i])
+	}
+	return strings.Join(captures, ", ")
+}
+
 // CheckSysDescr checks the sysDescr value of an SNMP target using a regular expression pattern.
-// It takes the SNMP client, the expected sysDescr regular expression pattern, and a boolean flag to enable performance data.
+// It takes the SNMP client, the expected sysDescr regular expression pattern, whether to invert the match, and a boolean
+// flag to enable performance data.
 // It returns a CheckResult struct with the result of the check and the performance data (if enabled).
+// It is CheckSysValue applied to sysDescr.
 //
-// The function retrieves the sysDescr value using the GetValue method of the SNMP client.
+// Example usage:
+//
+//	snmpClient := snmp.Client{
+//	    Target:    "127.0.0.1",
+//	    Community: "public",
+//	}
+//	result := CheckSysDescr(&snmpClient, "Cisco", false, true)
+//	result.SendResult()
+func CheckSysDescr(snmpClient *snmp.Client, expectedSysDescrRegExp string, invert bool, enablePerfData bool) *gomonitor.CheckResult {
+	return CheckSysValue(snmpClient, oidSysDescr, expectedSysDescrRegExp, invert, enablePerfData)
+}
+
+// CheckSysValue checks the value of an OID of an SNMP target, such as sysName or sysObjectID, using a regular expression pattern.
+// It takes the SNMP client, the OID, the expected regular expression pattern, whether to invert the match, and a boolean
+// flag to enable performance data.
+// It returns a CheckResult struct with the result of the check and the performance data (if enabled).
+//
+// The function retrieves the value using the GetValue method of the SNMP client.
 // If an error occurs while retrieving the value, a critical check result is returned with an error message.
 //
-// If the expectedSysDescrRegExp is provided, the function compares the sysDescr value with the regular expression pattern.
+// If the expectedRegExp is provided, the function compares the value with the regular expression pattern.
 // If it does not match, a critical check result is returned with an error message.
+// If invert is true, the pattern is a forbidden one instead, e.g. a known-vulnerable firmware version:
+// a match returns a critical check result and a non-match is OK.
+// An invalid pattern always returns a critical check result.
 //
-// Otherwise, an OK check result is returned with the sysDescr value.
+// Otherwise, an OK check result is returned with the value. When the pattern has named capture groups, the
+// matched values are appended to the message, e.g. "(ver=15.2(4))", to extract versions in the same pass.
 //
 // If enablePerfData is true, the function adds the SNMP latency to the performance data of the check result.
 // The latency is measured as the duration of the SNMP request.
 //
 // Example usage:
 //
-//	snmpClient := snmp.Client{
-//	    Target:    "127.0.0.1",
-//	    Community: "public",
-//	}
-//	result := CheckSysDescr(&snmpClient, "Cisco", true)
+//	result := CheckSysValue(&snmpClient, "1.3.6.1.2.1.1.5.0", `^[a-z]{3}-sw\d+$`, false, true)
 //	result.SendResult()
-func CheckSysDescr(snmpClient *snmp.Client, expectedSysDescrRegExp string, enablePerfData bool) *gomonitor.CheckResult {
-	oids := []string{"1.3.6.1.2.1.1.1.0"}
+func CheckSysValue(snmpClient *snmp.Client, oid string, expectedRegExp string, invert bool, enablePerfData bool) *gomonitor.CheckResult {
+	oids := []string{oid}
+	label := oidLabel(oid)
 
 	result, latency, err := snmpClient.GetValue(oids)
 	if err != nil {
 		checkResult := gomonitor.NewCheckResult()
 		eMessage := fmt.Sprintf("SNMP target %s failed to return data for requested OID.", snmpClient.Target)
-		checkResult.SetResult(gomonitor.Critical, eMessage)
+		checkResult.SetResult(plugin.ErrorStatus(err), eMessage)
 		return checkResult
 	}
 
 	checkResult := gomonitor.NewCheckResult()
-	sysDescr := string(result.Variables[0].Value.([]uint8))
+	if result.Variables[0].Value == nil {
+		eMessage := fmt.Sprintf("SNMP target %s returned no value for %s", snmpClient.Target, label)
+		checkResult.SetResult(gomonitor.Critical, eMessage)
+		return checkResult
+	}
+	value := snmp.ToString(result.Variables[0].Value)
+	message := fmt.Sprintf("%s", value)
 
-	// Compare result with expected sysDescr using regexp
-	if expectedSysDescrRegExp != "" {
-		match, err := regexp.MatchString(expectedSysDescrRegExp, sysDescr)
-		if err != nil || !match {
-			eMessage := fmt.Sprintf("sysDescr does not match expected pattern '%s'. Got: %s", expectedSysDescrRegExp, sysDescr)
+	// Compare result with expected value using regexp
+	if expectedRegExp != "" {
+		pattern, err := regexp.Compile(expectedRegExp)
+		if err != nil {
+			eMessage := fmt.Sprintf("Invalid pattern '%s': %s", expectedRegExp, err)
 			checkResult.SetResult(gomonitor.Critical, eMessage)
 			return checkResult
 		}
+		submatches := pattern.FindStringSubmatch(value)
+		match := submatches != nil
+		if invert && match {
+			eMessage := fmt.Sprintf("%s matches forbidden pattern '%s'. Got: %s", label, expectedRegExp, value)
+			checkResult.SetResult(gomonitor.Critical, eMessage)
+			return checkResult
+		}
+		if !invert && !match {
+			eMessage := fmt.Sprintf("%s does not match expected pattern '%s'. Got: %s", label, expectedRegExp, value)
+			checkResult.SetResult(gomonitor.Critical, eMessage)
+			return checkResult
+		}
+		if captures := namedCaptures(pattern, submatches); captures != "" {
+			message += " (" + captures + ")"
+		}
 	}
-	message := fmt.Sprintf("%s", sysDescr)
 	checkResult.SetResult(gomonitor.OK, message)
 
 	if enablePerfData {
@@ -81,19 +158,26 @@ func CheckSysDescr(snmpClient *snmp.Client, expectedSysDescrRegExp string, enabl
 }
 
 // main is the entry point of the program. It parses command-line flags, creates an SNMP client,
-// and performs a check on the target SNMP device using the CheckSysDescr function.
+// and performs a check on the target SNMP device using the CheckSysValue function.
 // The result of the check is then sent using the SendResult method.
 func main() {
-	target := flag.String("target", "127.0.0.1", "The target SNMP device.")
-	community := flag.String("community", "public", "The SNMP community string.")
-	expectedSysDescrRegExp := flag.String("sysDescrPattern", "", "Regex pattern sysDescr to be matched. If not provided, any sysDescr will be accepted.")
+	snmpFlags := snmp.Flags{}
+	snmpFlags.Register()
+	oid := flag.String("oid", oidSysDescr, "The OID to match, e.g. 1.3.6.1.2.1.1.5.0 for sysName. Default is sysDescr.")
+	expectedSysDescrRegExp := flag.String("sysDescrPattern", "", "Regex pattern the value of -oid must match. If not provided, any value will be accepted.")
+	invert := flag.Bool("invert", false, "Treat -sysDescrPattern as a forbidden pattern: a match is Critical and a non-match is OK. Default is false.")
 	enablePerfData := flag.Bool("enablePerfData", false, "Enable performance data. Default is false.")
-	flag.Parse()
+	if err := config.Parse(); err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
 
-	snmpClient := snmp.Client{
-		Target:    *target,
-		Community: *community,
+	snmpClient, err := snmpFlags.Client()
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+	if snmpFlags.Validate {
+		plugin.Validate(&snmpClient)
 	}
-	result := CheckSysDescr(&snmpClient, *expectedSysDescrRegExp, *enablePerfData)
+	result := CheckSysValue(&snmpClient, *oid, *expectedSysDescrRegExp, *invert, *enablePerfData)
 	result.SendResult()
 }
@@ -0,0 +1,254 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/dmabry/gochecks/internal/config"
+	"github.com/dmabry/gochecks/internal/plugin"
+	"github.com/dmabry/gochecks/internal/snmp"
+	"github.com/dmabry/gochecks/internal/threshold"
+	"github.com/dmabry/gomonitor"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The ENTITY-SENSOR-MIB entPhySensorEntry and ENTITY-MIB entPhysicalName OIDs, and the Cisco
+// CISCO-ENVMON-MIB ciscoEnvMonTemperatureStatusEntry OID for devices without ENTITY-SENSOR-MIB.
+const (
+	oidEntPhySensorEntry                 = ".1.3.6.1.2.1.99.1.1.1"
+	oidEntPhysicalName                   = ".1.3.6.1.2.1.47.1.1.1.1.7"
+	oidCiscoEnvMonTemperatureStatusEntry = ".1.3.6.1.4.1.9.9.13.1.3.1"
+)
+
+// The entPhySensorType, entPhySensorScale and entPhySensorOperStatus values and the table columns read by the check.
+const (
+	entPhySensorTypeCelsius           = 8
+	entPhySensorScaleUnits            = 9
+	entPhySensorOperStatusOk          = 1
+	entPhySensorColumnType            = 1
+	entPhySensorColumnScale           = 2
+	entPhySensorColumnPrecision       = 3
+	entPhySensorColumnValue           = 4
+	entPhySensorColumnOperStatus      = 5
+	ciscoEnvMonTemperatureColumnDescr = 2
+	ciscoEnvMonTemperatureColumnValue = 3
+)
+
+// Sensor is a temperature reading in degrees Celsius.
+type Sensor struct {
+	Name        string
+	Temperature float64
+}
+
+// sensorTemperature converts a raw entPhySensorValue to a real value using entPhySensorScale, the
+// SI prefix as an exponent of 1000 where units (9) is no prefix, and entPhySensorPrecision, the
+// number of decimal places.
+func sensorTemperature(value float64, scale int, precision int) float64 {
+	return value * math.Pow(1000, float64(scale-entPhySensorScaleUnits)) / math.Pow(10, float64(precision))
+}
+
+// rowFloat returns the numeric value of a column of a table row, or 0 when missing or not numeric.
+func rowFloat(row map[int]interface{}, column int) float64 {
+	value, err := snmp.ToFloat64(row[column])
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+// GetEntitySensors walks entPhySensorTable and returns the operational temperature sensors named by their
+// entPhysicalName, or "sensor <index>" when the entity has no name.
+func GetEntitySensors(snmpClient *snmp.Client) ([]Sensor, error) {
+	return snmp.WithSession(snmpClient, getEntitySensors)
+}
+
+// getEntitySensors walks entPhySensorTable and entPhysicalName over session for GetEntitySensors.
+func getEntitySensors(session *snmp.Session) ([]Sensor, error) {
+	table, _, err := session.GetTable(oidEntPhySensorEntry)
+	if err != nil {
+		return nil, err
+	}
+	names, _, err := session.Walk(oidEntPhysicalName)
+	if err != nil {
+		return nil, err
+	}
+
+	var sensors []Sensor
+	for index, row := range table {
+		if rowFloat(row, entPhySensorColumnType) != entPhySensorTypeCelsius {
+			continue
+		}
+		if rowFloat(row, entPhySensorColumnOperStatus) != entPhySensorOperStatusOk {
+			continue
+		}
+		name := "sensor " + index
+		if value, ok := names[oidEntPhysicalName+"."+index]; ok && snmp.ToString(value) != "" {
+			name = snmp.ToString(value)
+		}
+		temperature := sensorTemperature(rowFloat(row, entPhySensorColumnValue), int(rowFloat(row, entPhySensorColumnScale)), int(rowFloat(row, entPhySensorColumnPrecision)))
+		sensors = append(sensors, Sensor{Name: name, Temperature: temperature})
+	}
+	sortSensors(sensors)
+	return sensors, nil
+}
+
+// GetCiscoSensors walks ciscoEnvMonTemperatureStatusTable and returns the temperature sensors named by
+// their ciscoEnvMonTemperatureStatusDescr.
+func GetCiscoSensors(snmpClient *snmp.Client) ([]Sensor, error) {
+	return snmp.WithSession(snmpClient, getCiscoSensors)
+}
+
+// getCiscoSensors walks ciscoEnvMonTemperatureStatusTable over session for GetCiscoSensors.
+func getCiscoSensors(session *snmp.Session) ([]Sensor, error) {
+	table, _, err := session.GetTable(oidCiscoEnvMonTemperatureStatusEntry)
+	if err != nil {
+		return nil, err
+	}
+
+	var sensors []Sensor
+	for index, row := range table {
+		if _, ok := row[ciscoEnvMonTemperatureColumnValue]; !ok {
+			continue
+		}
+		name := snmp.ToString(row[ciscoEnvMonTemperatureColumnDescr])
+		if _, ok := row[ciscoEnvMonTemperatureColumnDescr]; !ok {
+			name = "sensor " + index
+		}
+		sensors = append(sensors, Sensor{Name: name, Temperature: rowFloat(row, ciscoEnvMonTemperatureColumnValue)})
+	}
+	sortSensors(sensors)
+	return sensors, nil
+}
+
+// sortSensors orders sensors by name so results are deterministic.
+func sortSensors(sensors []Sensor) {
+	sort.Slice(sensors, func(i, j int) bool { return sensors[i].Name < sensors[j].Name })
+}
+
+// GetSensors returns the temperature sensors of the SNMP target from the MIB selected by vendor:
+// "entity" for ENTITY-SENSOR-MIB, "cisco" for CISCO-ENVMON-MIB, or "auto" to use ENTITY-SENSOR-MIB and
+// fall back to CISCO-ENVMON-MIB when it has no temperature sensors.
+func GetSensors(snmpClient *snmp.Client, vendor string) ([]Sensor, error) {
+	switch vendor {
+	case "entity":
+		return GetEntitySensors(snmpClient)
+	case "cisco":
+		return GetCiscoSensors(snmpClient)
+	case "auto":
+		sensors, err := GetEntitySensors(snmpClient)
+		if err != nil || len(sensors) > 0 {
+			return sensors, err
+		}
+		return GetCiscoSensors(snmpClient)
+	default:
+		return nil, fmt.Errorf("invalid vendor '%s', expected auto, entity or cisco", vendor)
+	}
+}
+
+// DetermineTemperature evaluates every sensor against the warn and crit ranges in degrees Celsius, where a
+// nil range disables that level. If enablePerf is true, the temperature of each sensor is added as
+// performance data.
+//
+// Example:
+//
+//	warn, _ := threshold.Parse("45")
+//	crit, _ := threshold.Parse("55")
+//	result := DetermineTemperature(sensors, &warn, &crit, true)
+//	result.SendResult()
+func DetermineTemperature(sensors []Sensor, warn *threshold.Range, crit *threshold.Range, enablePerf bool) *gomonitor.CheckResult {
+	checkResult := gomonitor.NewCheckResult()
+	if len(sensors) == 0 {
+		checkResult.SetResult(gomonitor.Unknown, "No temperature sensors found")
+		return checkResult
+	}
+
+	status := gomonitor.OK
+	var problems []string
+	for _, sensor := range sensors {
+		perfData := gomonitor.PerformanceMetric{Value: sensor.Temperature, UnitOM: "C"}
+		description := sensor.Name + " " + strconv.FormatFloat(sensor.Temperature, 'f', -1, 64) + "C"
+		if warn != nil {
+			perfData.Warn = warn.Bound()
+		}
+		if crit != nil {
+			perfData.Crit = crit.Bound()
+		}
+		if crit != nil && crit.Breaches(sensor.Temperature) {
+			status = gomonitor.Critical
+			problems = append(problems, description)
+		} else if warn != nil && warn.Breaches(sensor.Temperature) {
+			if status == gomonitor.OK {
+				status = gomonitor.Warning
+			}
+			problems = append(problems, description)
+		}
+		if enablePerf {
+			checkResult.AddPerformanceData(sensor.Name, perfData)
+		}
+	}
+
+	message := fmt.Sprintf("%d temperature sensor(s) checked", len(sensors))
+	if len(problems) > 0 {
+		message = "Temperature exceeds threshold: " + strings.Join(problems, ", ")
+	}
+	checkResult.SetResult(status, message)
+	return checkResult
+}
+
+// main is the entry point of the program. It parses command-line flags, creates an SNMP client,
+// reads the temperature sensors of the target SNMP device and evaluates them using the DetermineTemperature function.
+// The result of the check is then sent using the SendResult method.
+func main() {
+	snmpFlags := snmp.Flags{}
+	snmpFlags.Register()
+	vendor := flag.String("vendor", "auto", "The MIB to read temperatures from: auto, entity (ENTITY-SENSOR-MIB) or cisco (CISCO-ENVMON-MIB).")
+	warn := flag.String("warn", "", "Warning range for the temperature in degrees Celsius, e.g. 45. If not provided, there is no warning level.")
+	crit := flag.String("crit", "", "Critical range for the temperature in degrees Celsius, e.g. 55. If not provided, there is no critical level.")
+	enablePerfData := flag.Bool("enablePerfData", false, "Enable performance data. Default is false.")
+	if err := config.Parse(); err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+
+	warnRange, err := threshold.ParseOptional(*warn)
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid warn: %s", err))
+	}
+	critRange, err := threshold.ParseOptional(*crit)
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid crit: %s", err))
+	}
+
+	snmpClient, err := snmpFlags.Client()
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+	if snmpFlags.Validate {
+		plugin.Validate(&snmpClient)
+	}
+	sensors, err := GetSensors(&snmpClient, *vendor)
+	if err != nil {
+		eMessage := fmt.Sprintf("SNMP target %s failed to return temperature sensors. %s", snmpClient.Target, err)
+		plugin.Exit(plugin.ErrorStatus(err), eMessage)
+	}
+
+	result := DetermineTemperature(sensors, warnRange, critRange, *enablePerfData)
+	result.SendResult()
+}
@@ -0,0 +1,146 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/dmabry/gochecks/internal/config"
+	"github.com/dmabry/gochecks/internal/plugin"
+	"github.com/dmabry/gochecks/internal/snmp"
+	"github.com/dmabry/gochecks/internal/util"
+	"github.com/dmabry/gomonitor"
+	"time"
+)
+
+// The uptime sources, in the order they are requested.
+// sysUpTime and hrSystemUptime are TimeTicks in hundredths of a second, which wrap after about 497 days.
+// snmpEngineTime is in seconds and only wraps after about 68 years.
+const (
+	oidSysUpTime      = ".1.3.6.1.2.1.1.3.0"
+	oidHrSystemUptime = ".1.3.6.1.2.1.25.1.1.0"
+	oidSnmpEngineTime = ".1.3.6.1.6.3.10.2.1.3.0"
+)
+
+// Uptime is the longest uptime reported by the agent and the object it was read from.
+type Uptime struct {
+	Duration time.Duration
+	Source   string
+}
+
+// longestUptime returns the longest of the uptimes reported in values, keyed by OID.
+// A reboot resets every source, while a wrapped TimeTicks counter only resets one of them, so the
+// longest uptime avoids false reboot alerts on devices up for more than 497 days.
+// Missing or unexpected values are skipped. It returns false when no source reported an uptime.
+func longestUptime(values map[string]interface{}) (Uptime, bool) {
+	var longest Uptime
+	found := false
+	for _, source := range []struct {
+		oid  string
+		name string
+		unit time.Duration
+	}{
+		{oidSysUpTime, "sysUpTime", 10 * time.Millisecond},
+		{oidHrSystemUptime, "hrSystemUptime", 10 * time.Millisecond},
+		{oidSnmpEngineTime, "snmpEngineTime", time.Second},
+	} {
+		var uptime time.Duration
+		switch val := values[source.oid].(type) {
+		case uint32:
+			uptime = time.Duration(val) * source.unit
+		case int:
+			uptime = time.Duration(val) * source.unit
+		default:
+			continue
+		}
+		if !found || uptime > longest.Duration {
+			longest = Uptime{Duration: uptime, Source: source.name}
+			found = true
+		}
+	}
+	return longest, found
+}
+
+// CheckUptime reads sysUpTime, hrSystemUptime and snmpEngineTime from the SNMP target in a single request
+// and alerts when the longest of them is below the warn or crit duration, which means the device recently rebooted.
+// A zero warn or crit disables that level.
+// If enablePerfData is true, the uptime in seconds and the SNMP latency are added as performance data.
+//
+// Example:
+//
+//	result := CheckUptime(&snmpClient, 1*time.Hour, 10*time.Minute, true)
+//	result.SendResult()
+func CheckUptime(snmpClient *snmp.Client, warn time.Duration, crit time.Duration, enablePerfData bool) *gomonitor.CheckResult {
+	checkResult := gomonitor.NewCheckResult()
+
+	result, latency, err := snmpClient.GetValue([]string{oidSysUpTime, oidHrSystemUptime, oidSnmpEngineTime})
+	if err != nil {
+		eMessage := fmt.Sprintf("SNMP target %s failed to return data for requested OIDs. %s", snmpClient.Target, err)
+		checkResult.SetResult(plugin.ErrorStatus(err), eMessage)
+		return checkResult
+	}
+
+	values := make(map[string]interface{})
+	for _, variable := range result.Variables {
+		values[variable.Name] = variable.Value
+	}
+	uptime, ok := longestUptime(values)
+	if !ok {
+		checkResult.SetResult(gomonitor.Unknown, fmt.Sprintf("SNMP target %s returned no uptime", snmpClient.Target))
+		return checkResult
+	}
+
+	message := fmt.Sprintf("Uptime %s (%s)", util.FormatUptime(uptime.Duration.Seconds()), uptime.Source)
+	switch {
+	case crit > 0 && uptime.Duration < crit:
+		checkResult.SetResult(gomonitor.Critical, "Device recently rebooted: "+message)
+	case warn > 0 && uptime.Duration < warn:
+		checkResult.SetResult(gomonitor.Warning, "Device recently rebooted: "+message)
+	default:
+		checkResult.SetResult(gomonitor.OK, message)
+	}
+
+	if enablePerfData {
+		checkResult.AddPerformanceData("uptime", gomonitor.PerformanceMetric{Value: uptime.Duration.Seconds(), Warn: warn.Seconds(), Crit: crit.Seconds(), Min: 0, UnitOM: "s"})
+		checkResult.AddPerformanceData("latency", gomonitor.PerformanceMetric{Value: latency.Seconds(), UnitOM: "s"})
+	}
+	return checkResult
+}
+
+// main is the entry point of the program. It parses command-line flags, creates an SNMP client,
+// and performs a check on the target SNMP device using the CheckUptime function.
+// The result of the check is then sent using the SendResult method.
+func main() {
+	snmpFlags := snmp.Flags{}
+	snmpFlags.Register()
+	warn := flag.Duration("warn", 0, "Warn when the uptime is below this duration, e.g. 1h. Default is 0 (disabled).")
+	crit := flag.Duration("crit", 0, "Critical when the uptime is below this duration, e.g. 10m. Default is 0 (disabled).")
+	enablePerfData := flag.Bool("enablePerfData", false, "Enable performance data. Default is false.")
+	if err := config.Parse(); err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+
+	snmpClient, err := snmpFlags.Client()
+	if err != nil {
+		plugin.Exit(gomonitor.Unknown, err.Error())
+	}
+	if snmpFlags.Validate {
+		plugin.Validate(&snmpClient)
+	}
+	result := CheckUptime(&snmpClient, *warn, *crit, *enablePerfData)
+	result.SendResult()
+}
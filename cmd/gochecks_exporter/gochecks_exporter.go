@@ -0,0 +1,391 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/dmabry/gochecks/internal/clock"
+	"github.com/dmabry/gochecks/internal/config"
+	"github.com/dmabry/gochecks/internal/interfaces"
+	"github.com/dmabry/gochecks/internal/snmp"
+	"github.com/dmabry/gochecks/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The tables collected on every scrape besides those of the interfaces and storage packages.
+// hrProcessorLoad is the HOST-RESOURCES-MIB average load of each processor over the last minute, in percent.
+// cpmCPUTotal5minRev is the CISCO-PROCESS-MIB busy percentage of each CPU over the last 5 minutes.
+const (
+	oidIfXEntry           = ".1.3.6.1.2.1.31.1.1.1"
+	oidHrProcessorLoad    = ".1.3.6.1.2.1.25.3.3.1.2"
+	oidCpmCPUTotal5minRev = ".1.3.6.1.4.1.9.9.109.1.1.1.1.8"
+)
+
+// Columns of the IF-MIB ifXEntry.
+const (
+	ifXColumnName      = 1
+	ifXColumnHCIn      = 6
+	ifXColumnHCOut     = 10
+	ifXColumnHighSpeed = 15
+)
+
+// defaultListen and defaultInterval are used when the config file does not set them.
+// Every metric name starts with metricPrefix.
+const (
+	defaultListen       = ":9116"
+	defaultInterval     = time.Minute
+	metricPrefix        = "gochecks_"
+	contentTypeTextV004 = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// Config is the exporter configuration file, e.g.
+//
+//	{
+//	    "listen": ":9116",
+//	    "interval": "1m",
+//	    "timeout": "5s",
+//	    "targets": [
+//	        {"target": "core-sw1", "community": "public"},
+//	        {"target": "192.0.2.10:1161"}
+//	    ]
+//	}
+//
+// Interval is the minimum time between two collections of a target. Scrapes within the interval are served
+// the previous results, so several Prometheus servers scraping the exporter do not multiply the SNMP load.
+type Config struct {
+	Listen   string         `json:"listen"`
+	Interval string         `json:"interval"`
+	Timeout  string         `json:"timeout"`
+	Targets  []TargetConfig `json:"targets"`
+}
+
+// TargetConfig is an SNMP target collected by the exporter. An empty community defaults to public.
+type TargetConfig struct {
+	Target    string `json:"target"`
+	Community string `json:"community"`
+}
+
+// LoadConfig reads and validates the exporter configuration file at path.
+func LoadConfig(path string) (*Config, time.Duration, time.Duration, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to read config file: %w", err)
+	}
+	cfg := &Config{}
+	if err := json.Unmarshal(content, cfg); err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if len(cfg.Targets) == 0 {
+		return nil, 0, 0, fmt.Errorf("config file %s has no targets", path)
+	}
+	if cfg.Listen == "" {
+		cfg.Listen = defaultListen
+	}
+	interval, timeout := defaultInterval, time.Duration(0)
+	if cfg.Interval != "" {
+		if interval, err = time.ParseDuration(cfg.Interval); err != nil {
+			return nil, 0, 0, fmt.Errorf("invalid interval in config file %s: %w", path, err)
+		}
+	}
+	if cfg.Timeout != "" {
+		if timeout, err = time.ParseDuration(cfg.Timeout); err != nil {
+			return nil, 0, 0, fmt.Errorf("invalid timeout in config file %s: %w", path, err)
+		}
+	}
+	for i, target := range cfg.Targets {
+		if target.Target == "" {
+			return nil, 0, 0, fmt.Errorf("target %d in config file %s has no target", i+1, path)
+		}
+		if target.Community == "" {
+			cfg.Targets[i].Community = "public"
+		}
+	}
+	return cfg, interval, timeout, nil
+}
+
+// Sample is a single value of a metric with its labels, given as name/value pairs.
+type Sample struct {
+	Labels []string
+	Value  float64
+}
+
+// Metric is a metric family in the Prometheus text exposition format.
+type Metric struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []Sample
+}
+
+// Metrics collects the metric families of a scrape by name.
+type Metrics map[string]*Metric
+
+// Add appends a sample to the named metric family, creating it on first use.
+// labels are name/value pairs, e.g. "target", "core-sw1", "ifname", "Gi0/1".
+func (metrics Metrics) Add(name string, kind string, help string, value float64, labels ...string) {
+	metric, ok := metrics[name]
+	if !ok {
+		metric = &Metric{Name: metricPrefix + name, Help: help, Type: kind}
+		metrics[name] = metric
+	}
+	metric.Samples = append(metric.Samples, Sample{Labels: labels, Value: value})
+}
+
+// Merge adds every sample of other to metrics.
+func (metrics Metrics) Merge(other Metrics) {
+	for name, metric := range other {
+		for _, sample := range metric.Samples {
+			metrics.Add(name, metric.Type, metric.Help, sample.Value, sample.Labels...)
+		}
+	}
+}
+
+// labelEscaper escapes label values as required by the text exposition format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WriteText renders the metrics in the Prometheus text exposition format, ordered by metric name.
+func (metrics Metrics) WriteText(w io.Writer) error {
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out strings.Builder
+	for _, name := range names {
+		metric := metrics[name]
+		fmt.Fprintf(&out, "# HELP %s %s\n# TYPE %s %s\n", metric.Name, metric.Help, metric.Name, metric.Type)
+		for _, sample := range metric.Samples {
+			out.WriteString(metric.Name)
+			if len(sample.Labels) > 0 {
+				out.WriteString("{")
+				for i := 0; i+1 < len(sample.Labels); i += 2 {
+					if i > 0 {
+						out.WriteString(",")
+					}
+					fmt.Fprintf(&out, `%s="%s"`, sample.Labels[i], labelEscaper.Replace(sample.Labels[i+1]))
+				}
+				out.WriteString("}")
+			}
+			fmt.Fprintf(&out, " %s\n", strconv.FormatFloat(sample.Value, 'g', -1, 64))
+		}
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// CollectInterfaces adds the 64-bit octet counters, link speed and oper status of every interface of the
+// target, labelled with its index and ifName.
+func CollectInterfaces(snmpClient *snmp.Client, metrics Metrics) error {
+	type walked struct {
+		table    snmp.Table
+		statuses map[string]interface{}
+	}
+	result, err := snmp.WithSession(snmpClient, func(session *snmp.Session) (walked, error) {
+		table, _, err := session.GetTable(oidIfXEntry)
+		if err != nil {
+			return walked{}, err
+		}
+		statuses, _, err := session.Walk(interfaces.OIDIfOperStatus)
+		if err != nil {
+			return walked{}, err
+		}
+		return walked{table: table, statuses: statuses}, nil
+	})
+	if err != nil {
+		return err
+	}
+	table, statuses := result.table, result.statuses
+
+	for index, row := range table {
+		labels := []string{"target", snmpClient.Target, "index", index, "ifname", snmp.ToString(row[ifXColumnName])}
+		if value, err := snmp.ToFloat64(row[ifXColumnHCIn]); err == nil {
+			metrics.Add("interface_in_octets_total", "counter", "Octets received on the interface (ifHCInOctets).", value, labels...)
+		}
+		if value, err := snmp.ToFloat64(row[ifXColumnHCOut]); err == nil {
+			metrics.Add("interface_out_octets_total", "counter", "Octets sent on the interface (ifHCOutOctets).", value, labels...)
+		}
+		if value, err := snmp.ToFloat64(row[ifXColumnHighSpeed]); err == nil {
+			metrics.Add("interface_speed_bps", "gauge", "Link speed of the interface in bits per second (ifHighSpeed).", value*1000000, labels...)
+		}
+		if value, err := snmp.ToFloat64(statuses[interfaces.OIDIfOperStatus+"."+index]); err == nil {
+			metrics.Add("interface_oper_status", "gauge", "Operational status of the interface (ifOperStatus), 1 is up.", value, labels...)
+		}
+	}
+	return nil
+}
+
+// CollectCPU adds the load of every processor of the target from the HOST-RESOURCES-MIB hrProcessorTable,
+// and the 5 minute utilization of every CPU from the CISCO-PROCESS-MIB cpmCPUTotalTable, which Cisco IOS
+// and IOS-XE devices implement instead. Either table may be empty.
+func CollectCPU(snmpClient *snmp.Client, metrics Metrics) error {
+	if err := collectCPUColumn(snmpClient, metrics, oidHrProcessorLoad, "cpu_load_percent", "Average load of the processor over the last minute (hrProcessorLoad)."); err != nil {
+		return err
+	}
+	return collectCPUColumn(snmpClient, metrics, oidCpmCPUTotal5minRev, "cpu_5min_percent", "Busy percentage of the CPU over the last 5 minutes (cpmCPUTotal5minRev).")
+}
+
+// collectCPUColumn walks the per-CPU percentage column given by columnOid and adds each value as the gauge
+// name, labelled with the row index.
+func collectCPUColumn(snmpClient *snmp.Client, metrics Metrics, columnOid string, name string, help string) error {
+	values, _, err := snmpClient.Walk(columnOid)
+	if err != nil {
+		return err
+	}
+	for oid, value := range values {
+		index, err := snmp.TableIndex(columnOid, oid)
+		if err != nil {
+			return err
+		}
+		percent, err := snmp.ToFloat64(value)
+		if err != nil {
+			continue
+		}
+		metrics.Add(name, "gauge", help, percent, "target", snmpClient.Target, "index", index)
+	}
+	return nil
+}
+
+// CollectStorage adds the size and used space in bytes of every hrStorageTable entry of the target,
+// which covers both memory and filesystems.
+func CollectStorage(snmpClient *snmp.Client, metrics Metrics) error {
+	units, err := storage.Collect(snmpClient)
+	if err != nil {
+		return err
+	}
+	for _, unit := range units {
+		labels := []string{"target", snmpClient.Target, "index", strconv.Itoa(unit.Index), "type", unit.Type, "description", unit.Description}
+		metrics.Add("storage_size_bytes", "gauge", "Size of the storage (hrStorageSize).", float64(unit.SizeBytes()), labels...)
+		metrics.Add("storage_used_bytes", "gauge", "Used space of the storage (hrStorageUsed).", float64(unit.UsedBytes()), labels...)
+	}
+	return nil
+}
+
+// CollectTarget runs every collector against the target and returns its metrics. The gochecks_up metric
+// is 1 when the interfaces could be collected, since every agent implements IF-MIB, and 0 otherwise.
+// The CPU and storage collectors are skipped with a log message on agents without HOST-RESOURCES-MIB.
+func CollectTarget(snmpClient *snmp.Client) Metrics {
+	metrics := make(Metrics)
+	start := clock.Now()
+
+	up := 1.0
+	if err := CollectInterfaces(snmpClient, metrics); err != nil {
+		slog.Warn("Failed to collect interfaces", "target", snmpClient.Target, "error", err)
+		up = 0
+	} else {
+		if err := CollectCPU(snmpClient, metrics); err != nil {
+			slog.Warn("Failed to collect CPU", "target", snmpClient.Target, "error", err)
+		}
+		if err := CollectStorage(snmpClient, metrics); err != nil {
+			slog.Warn("Failed to collect storage", "target", snmpClient.Target, "error", err)
+		}
+	}
+
+	metrics.Add("up", "gauge", "Whether the SNMP target could be collected.", up, "target", snmpClient.Target)
+	metrics.Add("collect_duration_seconds", "gauge", "Time spent collecting the SNMP target.", clock.Since(start).Seconds(), "target", snmpClient.Target)
+	return metrics
+}
+
+// Exporter serves the metrics of its targets, collecting them concurrently at most once per interval.
+type Exporter struct {
+	Clients  []snmp.Client
+	Interval time.Duration
+
+	mu        sync.Mutex
+	collected time.Time
+	metrics   Metrics
+}
+
+// Collect returns the metrics of every target, collecting them again when the previous results are
+// older than the interval.
+func (exporter *Exporter) Collect() Metrics {
+	exporter.mu.Lock()
+	defer exporter.mu.Unlock()
+
+	if exporter.metrics != nil && clock.Since(exporter.collected) < exporter.Interval {
+		return exporter.metrics
+	}
+
+	results := make([]Metrics, len(exporter.Clients))
+	var wg sync.WaitGroup
+	for i := range exporter.Clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = CollectTarget(&exporter.Clients[i])
+		}(i)
+	}
+	wg.Wait()
+
+	metrics := make(Metrics)
+	for _, result := range results {
+		metrics.Merge(result)
+	}
+	exporter.metrics = metrics
+	exporter.collected = clock.Now()
+	return metrics
+}
+
+// ServeHTTP renders the metrics of every target for a Prometheus scrape.
+func (exporter *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", contentTypeTextV004)
+	if err := exporter.Collect().WriteText(w); err != nil {
+		slog.Warn("Failed to write metrics", "error", err)
+	}
+}
+
+// main is the entry point of the program. It loads the configuration file and serves the metrics of the
+// configured targets on /metrics until it is stopped.
+func main() {
+	configPath := flag.String("config", "", "The JSON configuration file with the listen address, collection interval and targets.")
+	if err := config.ParseWithoutConfig(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if *configPath == "" {
+		fmt.Fprintln(os.Stderr, "-config is required")
+		os.Exit(2)
+	}
+	cfg, interval, timeout, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	exporter := &Exporter{Interval: interval}
+	for _, target := range cfg.Targets {
+		exporter.Clients = append(exporter.Clients, snmp.Client{Target: target.Target, Community: target.Community, Timeout: timeout})
+	}
+
+	http.Handle("/metrics", exporter)
+	slog.Info("Serving metrics", "targets", len(exporter.Clients), "listen", cfg.Listen, "path", "/metrics")
+	if err := http.ListenAndServe(cfg.Listen, nil); err != nil {
+		slog.Error("Failed to serve metrics", "listen", cfg.Listen, "error", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,65 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/dmabry/gochecks/internal/clock"
+	"github.com/dmabry/gochecks/internal/interfaces"
+	"github.com/dmabry/gochecks/internal/snmp"
+	"github.com/dmabry/gochecks/internal/snmp/snmptest"
+	"testing"
+	"time"
+)
+
+func TestExporterCollectInterval(t *testing.T) {
+	previous := clock.Default
+	t.Cleanup(func() { clock.Default = previous })
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	agent := snmptest.NewAgent(t, map[string]interface{}{
+		interfaces.OIDIfName + ".1":       "Gi0/1",
+		interfaces.OIDIfHCInOctets + ".1": uint64(1000),
+		interfaces.OIDIfOperStatus + ".1": 1,
+	})
+	exporter := &Exporter{
+		Clients:  []snmp.Client{{Target: agent.Target, Community: "public", Timeout: time.Second, Retries: -1}},
+		Interval: time.Minute,
+	}
+
+	tests := []struct {
+		name        string
+		elapsed     time.Duration
+		wantCollect bool
+	}{
+		{"first scrape collects", 0, true},
+		{"scrape within the interval is cached", 59 * time.Second, false},
+		{"scrape after the interval collects", time.Minute, true},
+		{"scrape within the new interval is cached", 90 * time.Second, false},
+	}
+	for _, tt := range tests {
+		clock.Default = clock.Fixed(start.Add(tt.elapsed))
+		requests := len(agent.Requests())
+
+		metrics := exporter.Collect()
+		if len(metrics) == 0 {
+			t.Errorf("%s: Collect returned no metrics", tt.name)
+		}
+		if collected := len(agent.Requests()) > requests; collected != tt.wantCollect {
+			t.Errorf("%s: Collect sent requests %t, want %t", tt.name, collected, tt.wantCollect)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"github.com/dmabry/gochecks/internal/config"
+	"github.com/dmabry/gochecks/internal/plugin"
+	"github.com/dmabry/gochecks/internal/snmp"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// formatValue renders an SNMP value as a CSV cell. Octet strings are written as text when printable,
+// and as hex otherwise, e.g. MAC addresses.
+func formatValue(value interface{}) string {
+	val, ok := value.([]byte)
+	if !ok {
+		return snmp.ToString(value)
+	}
+	if !utf8.Valid(val) {
+		return hex.EncodeToString(val)
+	}
+	for _, r := range string(val) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return hex.EncodeToString(val)
+		}
+	}
+	return string(val)
+}
+
+// splitColumns splits the OIDs walked under baseOid into the column and the row index of each.
+// The column is the first arc after baseOid, so walking a table entry such as ifEntry ".1.3.6.1.2.1.2.2.1"
+// yields one column per ifEntry column. When every OID shares the same first arc and still has a
+// column and an index after it, as when walking a table such as ifTable ".1.3.6.1.2.1.2.2", that
+// arc is the entry and is added to the base instead.
+// It returns the base the columns are relative to and, for each OID, its column and row index.
+func splitColumns(baseOid string, oids []string) (string, [][2]string, error) {
+	base := "." + strings.Trim(baseOid, ".")
+	suffixes := make([]string, len(oids))
+	for i, oid := range oids {
+		suffix, found := strings.CutPrefix(oid, base+".")
+		if !found {
+			return "", nil, fmt.Errorf("OID %s is not under %s", oid, base)
+		}
+		suffixes[i] = suffix
+	}
+
+	if len(suffixes) > 0 {
+		entry, _, _ := strings.Cut(suffixes[0], ".")
+		isEntry := true
+		for _, suffix := range suffixes {
+			first, rest, found := strings.Cut(suffix, ".")
+			if !found || first != entry || !strings.Contains(rest, ".") {
+				isEntry = false
+				break
+			}
+		}
+		if isEntry {
+			base += "." + entry
+			for i, suffix := range suffixes {
+				suffixes[i] = strings.TrimPrefix(suffix, entry+".")
+			}
+		}
+	}
+
+	cells := make([][2]string, len(suffixes))
+	for i, suffix := range suffixes {
+		column, index, found := strings.Cut(suffix, ".")
+		if !found {
+			// A scalar such as sysDescr.0 walked directly has no column
+			column, index = "", suffix
+		}
+		cells[i] = [2]string{column, index}
+	}
+	return base, cells, nil
+}
+
+// WriteCSV walks baseOid on the SNMP target and writes the table to w as CSV, with one row per table
+// index and one column per column OID, in the order they were walked.
+//
+// Example:
+//
+//	err := WriteCSV(&snmpClient, ".1.3.6.1.2.1.2.2", os.Stdout)
+func WriteCSV(snmpClient *snmp.Client, baseOid string, w io.Writer) error {
+	pdus, _, err := snmpClient.WalkOrdered(baseOid)
+	if err != nil {
+		return err
+	}
+
+	oids := make([]string, len(pdus))
+	for i, pdu := range pdus {
+		oids[i] = pdu.OID
+	}
+	base, cells, err := splitColumns(baseOid, oids)
+	if err != nil {
+		return err
+	}
+
+	var columns, indexes []string
+	columnSeen := make(map[string]bool)
+	rows := make(map[string]map[string]string)
+	for i, pdu := range pdus {
+		column, index := cells[i][0], cells[i][1]
+		if !columnSeen[column] {
+			columnSeen[column] = true
+			columns = append(columns, column)
+		}
+		if _, ok := rows[index]; !ok {
+			rows[index] = make(map[string]string)
+			indexes = append(indexes, index)
+		}
+		rows[index][column] = formatValue(pdu.Value)
+	}
+
+	writer := csv.NewWriter(w)
+	header := []string{"index"}
+	for _, column := range columns {
+		if column == "" {
+			header = append(header, base)
+		} else {
+			header = append(header, base+"."+column)
+		}
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		record := []string{index}
+		for _, column := range columns {
+			record = append(record, rows[index][column])
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// main is the entry point of the program. It parses command-line flags, creates an SNMP client,
+// and writes the table under the base OID to stdout as CSV using the WriteCSV function.
+func main() {
+	snmpFlags := snmp.Flags{}
+	snmpFlags.Register()
+	oid := flag.String("oid", "", "The base OID of the table to walk, e.g. .1.3.6.1.2.1.2.2 for ifTable.")
+	if err := config.Parse(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if *oid == "" {
+		fmt.Fprintln(os.Stderr, "-oid is required")
+		os.Exit(2)
+	}
+
+	snmpClient, err := snmpFlags.Client()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if snmpFlags.Validate {
+		plugin.Validate(&snmpClient)
+	}
+	if err := WriteCSV(&snmpClient, *oid, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "SNMP target %s failed to return data for %s: %s\n", snmpClient.Target, *oid, err)
+		os.Exit(1)
+	}
+}
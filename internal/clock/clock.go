@@ -0,0 +1,61 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package clock
+
+import (
+	"time"
+)
+
+// Clock tells the current time. Time-dependent code asks the package-level Default clock
+// instead of calling time.Now directly, so tests can inject a controlled clock.
+type Clock interface {
+	Now() time.Time
+}
+
+// Real is a Clock backed by the system time.
+type Real struct{}
+
+// Now returns the current system time.
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// Fixed is a Clock that always returns the same instant.
+//
+// Example usage:
+//
+//	clock.Default = clock.Fixed(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+//	defer func() { clock.Default = clock.Real{} }()
+type Fixed time.Time
+
+// Now returns the fixed instant.
+func (f Fixed) Now() time.Time {
+	return time.Time(f)
+}
+
+// Default is the Clock used throughout gochecks. It defaults to real time.
+var Default Clock = Real{}
+
+// Now returns the current time according to the Default clock.
+func Now() time.Time {
+	return Default.Now()
+}
+
+// Since returns the time elapsed since t according to the Default clock.
+func Since(t time.Time) time.Duration {
+	return Default.Now().Sub(t)
+}
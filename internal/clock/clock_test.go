@@ -0,0 +1,62 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+// useClock makes c the Default clock until the test ends.
+func useClock(t *testing.T, c Clock) {
+	t.Helper()
+	previous := Default
+	Default = c
+	t.Cleanup(func() { Default = previous })
+}
+
+func TestFixed(t *testing.T) {
+	instant := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	useClock(t, Fixed(instant))
+
+	if now := Now(); !now.Equal(instant) {
+		t.Errorf("Now() = %s, want %s", now, instant)
+	}
+	if now := Now(); !now.Equal(instant) {
+		t.Errorf("second Now() = %s, want the same %s", now, instant)
+	}
+	if elapsed := Since(instant.Add(-90 * time.Second)); elapsed != 90*time.Second {
+		t.Errorf("Since = %s, want 1m30s", elapsed)
+	}
+	if elapsed := Since(instant.Add(time.Minute)); elapsed != -time.Minute {
+		t.Errorf("Since a later instant = %s, want -1m0s", elapsed)
+	}
+}
+
+func TestReal(t *testing.T) {
+	useClock(t, Real{})
+
+	before := time.Now()
+	now := Now()
+	after := time.Now()
+	if now.Before(before) || now.After(after) {
+		t.Errorf("Now() = %s, want between %s and %s", now, before, after)
+	}
+	if elapsed := Since(before); elapsed < 0 {
+		t.Errorf("Since = %s, want a non-negative duration", elapsed)
+	}
+}
@@ -0,0 +1,164 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package config lets the check commands read their flags from a JSON file, so device-specific
+// settings can be kept in version control instead of long command lines.
+//
+// A config file is a JSON object keyed by flag name, e.g.
+//
+//	{
+//	    "target": "core-sw1",
+//	    "communityFile": "/etc/gochecks/community",
+//	    "timeout": "5s",
+//	    "warn": "80",
+//	    "enablePerfData": true
+//	}
+//
+// Flags given explicitly on the command line take precedence over the file.
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/dmabry/gochecks/internal/logging"
+	"github.com/dmabry/gochecks/internal/version"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// configFlag is the name of the flag that selects the config file.
+// versionFlag is the name of the flag that prints the build of the binary.
+const (
+	configFlag  = "config"
+	versionFlag = "version"
+)
+
+// Parse defines the -config, -version and -logLevel flags, parses the command-line flags and then applies
+// the config file, if one was given, to every flag that was not set explicitly. It replaces flag.Parse in main.
+// With -version, the build of the binary is printed and the program exits with status 0.
+// The default logger is then set up at -logLevel, or at debug level when the command's -logValues or -debug is set.
+//
+// Example usage:
+//
+//	snmpFlags := snmp.Flags{}
+//	snmpFlags.Register()
+//	warn := flag.String("warn", "", "Warning range")
+//	if err := config.Parse(); err != nil {
+//	    plugin.Exit(gomonitor.Unknown, err.Error())
+//	}
+func Parse() error {
+	return ParseFlagSet(flag.CommandLine, os.Args[1:])
+}
+
+// ParseWithoutConfig is like Parse, but does not define -config, for commands whose own -config flag names
+// a file of another format, such as the targets file of the exporter.
+func ParseWithoutConfig() error {
+	return parseFlagSet(flag.CommandLine, os.Args[1:], false)
+}
+
+// ParseFlagSet is like Parse, but parses args with the given flag set.
+func ParseFlagSet(fs *flag.FlagSet, args []string) error {
+	return parseFlagSet(fs, args, true)
+}
+
+// parseFlagSet implements ParseFlagSet, defining -config only when withConfig is set.
+func parseFlagSet(fs *flag.FlagSet, args []string, withConfig bool) error {
+	path := new(string)
+	if withConfig {
+		path = fs.String(configFlag, "", "A JSON file of flag values keyed by flag name. Flags given on the command line take precedence.")
+	}
+	showVersion := fs.Bool(versionFlag, false, "Print the version, git commit and build date and exit.")
+	logLevel := fs.String("logLevel", logging.DefaultLevel, "The minimum level of the diagnostics logged to stderr: debug, info, warn or error.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *showVersion {
+		version.Print(os.Stdout)
+		os.Exit(0)
+	}
+	if *path != "" {
+		if err := Apply(fs, *path); err != nil {
+			return err
+		}
+	}
+
+	level := *logLevel
+	for _, name := range []string{"logValues", "debug"} {
+		if debugFlag := fs.Lookup(name); debugFlag != nil && debugFlag.Value.String() == "true" {
+			level = "debug"
+		}
+	}
+	return logging.Setup(level)
+}
+
+// Apply reads the JSON config file at path and sets each flag it names, unless the flag was already
+// set explicitly. Values may be strings, numbers or booleans and are parsed like their command-line form.
+// It returns an error when the file cannot be read, names an unknown flag or holds an invalid value.
+func Apply(fs *flag.FlagSet, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(content, &values); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if name == configFlag || name == versionFlag || fs.Lookup(name) == nil {
+			return fmt.Errorf("config file %s sets unknown option '%s'", path, name)
+		}
+		if explicit[name] {
+			continue
+		}
+		value, err := flagValue(values[name])
+		if err != nil {
+			return fmt.Errorf("config file %s option '%s': %w", path, name, err)
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("config file %s option '%s': %w", path, name, err)
+		}
+	}
+
+	return nil
+}
+
+// flagValue returns the command-line form of a JSON value.
+func flagValue(value interface{}) (string, error) {
+	switch val := value.(type) {
+	case string:
+		return val, nil
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v, expected a string, number or boolean", value)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package graphite sends metrics to a Carbon daemon using the Graphite plaintext protocol,
+// one "path value timestamp" line per metric.
+package graphite
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// unsafeChars matches the characters of a path node that Graphite would misinterpret,
+// such as the dots separating nodes and the spaces separating the fields of a line.
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// Metric is a single data point sent to Carbon.
+type Metric struct {
+	Path      string
+	Value     float64
+	Timestamp time.Time
+}
+
+// String returns the metric as a plaintext protocol line without the trailing newline.
+func (m Metric) String() string {
+	return fmt.Sprintf("%s %s %d", m.Path, strconv.FormatFloat(m.Value, 'f', -1, 64), m.Timestamp.Unix())
+}
+
+// SanitizeNode makes s safe to use as a single node of a metric path by replacing every run of
+// characters other than letters, digits, underscores and dashes with an underscore.
+//
+// Example usage:
+//
+//	graphite.SanitizeNode("GigabitEthernet0/1.100") // "GigabitEthernet0_1_100"
+func SanitizeNode(s string) string {
+	return strings.Trim(unsafeChars.ReplaceAllString(s, "_"), "_")
+}
+
+// Path joins the given nodes into a metric path, sanitizing each of them. Empty nodes are skipped,
+// so an empty prefix does not produce a leading dot.
+//
+// Example usage:
+//
+//	graphite.Path("network", "192.0.2.1", "Gi0/1", "in_bps") // "network.192_0_2_1.Gi0_1.in_bps"
+func Path(nodes ...string) string {
+	var parts []string
+	for _, node := range nodes {
+		if node = SanitizeNode(node); node != "" {
+			parts = append(parts, node)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// Send writes the metrics to the Carbon plaintext listener at address, e.g. "graphite:2003",
+// over TCP. The connection and the write must complete within timeout.
+func Send(address string, timeout time.Duration, metrics []Metric) error {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to carbon at %s: %w", address, err)
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return fmt.Errorf("failed to set deadline on carbon connection: %w", err)
+	}
+	var lines strings.Builder
+	for _, metric := range metrics {
+		lines.WriteString(metric.String())
+		lines.WriteString("\n")
+	}
+	if _, err := conn.Write([]byte(lines.String())); err != nil {
+		return fmt.Errorf("failed to send metrics to carbon at %s: %w", address, err)
+	}
+
+	return nil
+}
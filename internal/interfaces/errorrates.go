@@ -0,0 +1,87 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package interfaces
+
+import (
+	"fmt"
+	"github.com/dmabry/gochecks/internal/clock"
+	"time"
+)
+
+// ErrorCounters holds the IF-MIB error and discard counters of a single interface.
+type ErrorCounters struct {
+	InErrors    uint64 `json:"in_errors"`
+	OutErrors   uint64 `json:"out_errors"`
+	InDiscards  uint64 `json:"in_discards"`
+	OutDiscards uint64 `json:"out_discards"`
+}
+
+// ErrorSample holds the ErrorCounters of every interface of a target keyed by ifIndex, along with the time they
+// were read, so it can be saved between runs to compute error rates.
+type ErrorSample struct {
+	Timestamp  time.Time             `json:"timestamp"`
+	Interfaces map[int]ErrorCounters `json:"interfaces"`
+}
+
+// ErrorRates holds the per-second rates of in+out errors and in+out discards of a single interface.
+type ErrorRates struct {
+	Errors   float64
+	Discards float64
+}
+
+// NewErrorSample returns the error and discard counters of deviceInterfaces, timestamped now.
+func NewErrorSample(deviceInterfaces map[int]*InterfaceDetail) ErrorSample {
+	sample := ErrorSample{Timestamp: clock.Now(), Interfaces: make(map[int]ErrorCounters, len(deviceInterfaces))}
+	for index, iface := range deviceInterfaces {
+		sample.Interfaces[index] = ErrorCounters{
+			InErrors:    uint64(iface.InErrors),
+			OutErrors:   uint64(iface.OutErrors),
+			InDiscards:  uint64(iface.InDiscards),
+			OutDiscards: uint64(iface.OutDiscards),
+		}
+	}
+	return sample
+}
+
+// ErrorRates returns the per-second error and discard rates of every interface present in both previous and
+// sample, keyed by ifIndex. The counters are 32-bit, so a counter that went backwards is taken to have wrapped.
+// Each counter is differenced on its own before the in and out rates are added, so the wrap of one is never
+// mistaken for a wrap of their sum. It returns an error when the samples are less than a second apart.
+func (sample ErrorSample) ErrorRates(previous ErrorSample) (map[int]ErrorRates, error) {
+	elapsed := sample.Timestamp.Sub(previous.Timestamp)
+	if elapsed < minPeriod {
+		return nil, fmt.Errorf("interval between samples %s is shorter than %s", elapsed, minPeriod)
+	}
+	period := elapsed.Seconds()
+
+	rates := make(map[int]ErrorRates, len(sample.Interfaces))
+	for index, current := range sample.Interfaces {
+		last, ok := previous.Interfaces[index]
+		if !ok {
+			continue
+		}
+		inErrors, _ := counterDelta(last.InErrors, current.InErrors, 32)
+		outErrors, _ := counterDelta(last.OutErrors, current.OutErrors, 32)
+		inDiscards, _ := counterDelta(last.InDiscards, current.InDiscards, 32)
+		outDiscards, _ := counterDelta(last.OutDiscards, current.OutDiscards, 32)
+		rates[index] = ErrorRates{
+			Errors:   float64(inErrors+outErrors) / period,
+			Discards: float64(inDiscards+outDiscards) / period,
+		}
+	}
+	return rates, nil
+}
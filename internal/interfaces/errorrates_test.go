@@ -0,0 +1,78 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package interfaces
+
+import (
+	"github.com/dmabry/gochecks/internal/clock"
+	"testing"
+	"time"
+)
+
+func TestNewErrorSample(t *testing.T) {
+	previous := clock.Default
+	clock.Default = clock.Fixed(testStart)
+	t.Cleanup(func() { clock.Default = previous })
+
+	sample := NewErrorSample(map[int]*InterfaceDetail{3: {InErrors: 1, OutErrors: 2, InDiscards: 3, OutDiscards: 4}})
+	if !sample.Timestamp.Equal(testStart) {
+		t.Errorf("Timestamp = %s, want %s", sample.Timestamp, testStart)
+	}
+	if counters := sample.Interfaces[3]; counters != (ErrorCounters{InErrors: 1, OutErrors: 2, InDiscards: 3, OutDiscards: 4}) {
+		t.Errorf("Interfaces[3] = %+v, want the counters of the interface", counters)
+	}
+}
+
+func TestErrorRates(t *testing.T) {
+	previous := ErrorSample{Timestamp: testStart, Interfaces: map[int]ErrorCounters{
+		1: {InErrors: 100, OutErrors: 100, InDiscards: 0, OutDiscards: 0},
+		2: {InErrors: 4294967290, OutErrors: 0, InDiscards: 4294967295, OutDiscards: 10},
+		3: {InErrors: 5},
+	}}
+	sample := ErrorSample{Timestamp: testStart.Add(10 * time.Second), Interfaces: map[int]ErrorCounters{
+		1: {InErrors: 150, OutErrors: 150, InDiscards: 0, OutDiscards: 0},
+		// The in counters wrap on their own, which must not be mistaken for a wrap of the in+out sum
+		2: {InErrors: 4, OutErrors: 0, InDiscards: 9, OutDiscards: 20},
+		4: {InErrors: 1000},
+	}}
+
+	rates, err := sample.ErrorRates(previous)
+	if err != nil {
+		t.Fatalf("ErrorRates returned error: %v", err)
+	}
+	want := map[int]ErrorRates{
+		1: {Errors: 10, Discards: 0},
+		2: {Errors: 1, Discards: 2},
+	}
+	if len(rates) != len(want) {
+		t.Errorf("ErrorRates returned %d interfaces, want only the %d present in both samples", len(rates), len(want))
+	}
+	for index, wantRates := range want {
+		if rates[index] != wantRates {
+			t.Errorf("rates of interface %d = %+v, want %+v", index, rates[index], wantRates)
+		}
+	}
+}
+
+func TestErrorRatesShortInterval(t *testing.T) {
+	previous := ErrorSample{Timestamp: testStart, Interfaces: map[int]ErrorCounters{1: {}}}
+	for _, elapsed := range []time.Duration{0, 500 * time.Millisecond, -time.Minute} {
+		sample := ErrorSample{Timestamp: testStart.Add(elapsed), Interfaces: map[int]ErrorCounters{1: {InErrors: 10}}}
+		if rates, err := sample.ErrorRates(previous); err == nil {
+			t.Errorf("ErrorRates over %s = %v, want an error", elapsed, rates)
+		}
+	}
+}
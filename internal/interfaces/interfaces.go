@@ -19,78 +19,118 @@ package interfaces
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/gosnmp/gosnmp"
+	"sort"
+	"strings"
 )
 
+// InterfaceDetail holds the IF-MIB details of a single interface. The JSON field names are
+// lower_snake_case and form a stable interface for downstream parsers.
 type InterfaceDetail struct {
 	// Basic Info
-	Description string
-	Name        string
-	Alias       string
-	PhysAddress string
+	Description string `json:"description"`
+	Name        string `json:"name"`
+	Alias       string `json:"alias"`
+	PhysAddress string `json:"phys_address"`
 
 	// Identification and Types
-	Index int
-	Type  int
-	MTU   int
+	Index int `json:"index"`
+	Type  int `json:"type"`
+	MTU   int `json:"mtu"`
 
 	// Speeds
-	Speed     uint
-	HighSpeed uint
+	Speed     uint `json:"speed"`
+	HighSpeed uint `json:"high_speed"`
 
 	// Status
-	OperStatus  int
-	AdminStatus int
+	OperStatus  int `json:"oper_status"`
+	AdminStatus int `json:"admin_status"`
+	Duplex      int `json:"duplex"`
 
 	// Octets
-	InOctets    uint
-	OutOctets   uint
-	HCInOctets  uint64
-	HCOutOctets uint64
+	InOctets    uint   `json:"in_octets"`
+	OutOctets   uint   `json:"out_octets"`
+	HCInOctets  uint64 `json:"hc_in_octets"`
+	HCOutOctets uint64 `json:"hc_out_octets"`
 
 	// Packets
-	InUcastPkts        uint
-	OutUcastPkts       uint
-	HCInUcastPkts      uint64
-	HCOutUcastPkts     uint64
-	InMulticastPkts    uint
-	OutMulticastPkts   uint
-	HCInMulticastPkts  uint64
-	HCOutMulticastPkts uint64
-	InBroadcastPkts    uint
-	OutBroadcastPkts   uint
-	HCInBroadcastPkts  uint64
-	HCOutBroadcastPkts uint64
-	InNUcastPkts       uint
-	OutNUcastPkts      uint
+	InUcastPkts        uint   `json:"in_ucast_pkts"`
+	OutUcastPkts       uint   `json:"out_ucast_pkts"`
+	HCInUcastPkts      uint64 `json:"hc_in_ucast_pkts"`
+	HCOutUcastPkts     uint64 `json:"hc_out_ucast_pkts"`
+	InMulticastPkts    uint   `json:"in_multicast_pkts"`
+	OutMulticastPkts   uint   `json:"out_multicast_pkts"`
+	HCInMulticastPkts  uint64 `json:"hc_in_multicast_pkts"`
+	HCOutMulticastPkts uint64 `json:"hc_out_multicast_pkts"`
+	InBroadcastPkts    uint   `json:"in_broadcast_pkts"`
+	OutBroadcastPkts   uint   `json:"out_broadcast_pkts"`
+	HCInBroadcastPkts  uint64 `json:"hc_in_broadcast_pkts"`
+	HCOutBroadcastPkts uint64 `json:"hc_out_broadcast_pkts"`
+	InNUcastPkts       uint   `json:"in_nucast_pkts"`
+	OutNUcastPkts      uint   `json:"out_nucast_pkts"`
 
 	// Errors and Discards
-	InErrors    uint
-	OutErrors   uint
-	InDiscards  uint
-	OutDiscards uint
+	InErrors    uint `json:"in_errors"`
+	OutErrors   uint `json:"out_errors"`
+	InDiscards  uint `json:"in_discards"`
+	OutDiscards uint `json:"out_discards"`
 
 	// Miscellaneous
-	LastChange               uint32
-	LinkUpDownTrapEnable     int
-	PromiscuousMode          int
-	ConnectorPresent         int
-	CounterDiscontinuityTime uint32
+	LastChange               uint32 `json:"last_change"`
+	LinkUpDownTrapEnable     int    `json:"link_up_down_trap_enable"`
+	PromiscuousMode          int    `json:"promiscuous_mode"`
+	ConnectorPresent         int    `json:"connector_present"`
+	CounterDiscontinuityTime uint32 `json:"counter_discontinuity_time"`
+}
+
+// TextField returns the value of the named text field of the interface, as used to select interfaces:
+// "name" (ifName), "descr" (ifDescr) or "alias" (ifAlias).
+func (ifaceDetail *InterfaceDetail) TextField(field string) (string, error) {
+	switch field {
+	case "name":
+		return ifaceDetail.Name, nil
+	case "descr":
+		return ifaceDetail.Description, nil
+	case "alias":
+		return ifaceDetail.Alias, nil
+	default:
+		return "", fmt.Errorf("unknown interface field '%s', expected name, descr or alias", field)
+	}
+}
+
+// IsLinkFault reports whether a physical connector is plugged into an administratively enabled
+// interface whose link is down, which usually points at a cable, optic or far-end fault.
+func (ifaceDetail *InterfaceDetail) IsLinkFault() bool {
+	return ifaceDetail.AdminStatus != StatusDown &&
+		ifaceDetail.OperStatus == StatusDown &&
+		ifaceDetail.ConnectorPresent == TruthValueTrue
+}
+
+// IsAdminUpOperDown reports whether an interface is administratively enabled but not operationally up.
+func (ifaceDetail *InterfaceDetail) IsAdminUpOperDown() bool {
+	return ifaceDetail.AdminStatus == StatusUp && ifaceDetail.OperStatus != StatusUp
+}
+
+// IsUnplugged reports whether an interface is down because no connector is plugged in, which is
+// the expected state of an unused port.
+func (ifaceDetail *InterfaceDetail) IsUnplugged() bool {
+	return ifaceDetail.OperStatus == StatusDown && ifaceDetail.ConnectorPresent == TruthValueFalse
 }
 
 func (ifaceDetail *InterfaceDetail) ToString(index int) string {
 	const (
-		outputFormat = "Interface index: %d\nDescription: %s\nAlias: %s\nName: %s\nType: %d\nSpeed: %d\nHighSpeed: %d\nOperStatus: %d\nAdminStatus: %d\nInOctets: %d\nOutOctets: %d\nHCInOctets: %d\nHCOutOctets: %d\nHCInUcastPkts: %d\nHCOutUcastPkts: %d\nInErrors: %d\nOutErrors: %d\nInUcastPkts: %d\nOutUcastPkts: %d\nInNUcastPkts: %d\nOutNUcastPkts: %d\nPromiscuousMode: %d\nLastChange: %d\nPhysAddress: %s\n\n"
+		outputFormat = "Interface index: %d\nDescription: %s\nAlias: %s\nName: %s\nType: %s\nSpeed: %d\nHighSpeed: %d\nOperStatus: %s\nAdminStatus: %s\nInOctets: %d\nOutOctets: %d\nHCInOctets: %d\nHCOutOctets: %d\nHCInUcastPkts: %d\nHCOutUcastPkts: %d\nInErrors: %d\nOutErrors: %d\nInUcastPkts: %d\nOutUcastPkts: %d\nInNUcastPkts: %d\nOutNUcastPkts: %d\nPromiscuousMode: %d\nLastChange: %d\nDuplex: %d\nPhysAddress: %s\n\n"
 	)
 	return fmt.Sprintf(outputFormat,
 		index,
 		ifaceDetail.Description,
 		ifaceDetail.Alias,
 		ifaceDetail.Name,
-		ifaceDetail.Type,
+		IfTypeName(ifaceDetail.Type),
 		ifaceDetail.Speed,
 		ifaceDetail.HighSpeed,
-		ifaceDetail.OperStatus,
-		ifaceDetail.AdminStatus,
+		IfStatusName(ifaceDetail.OperStatus),
+		IfStatusName(ifaceDetail.AdminStatus),
 		ifaceDetail.InOctets,
 		ifaceDetail.OutOctets,
 		ifaceDetail.HCInOctets,
@@ -105,6 +145,7 @@ func (ifaceDetail *InterfaceDetail) ToString(index int) string {
 		ifaceDetail.OutNUcastPkts,
 		ifaceDetail.PromiscuousMode,
 		ifaceDetail.LastChange,
+		ifaceDetail.Duplex,
 		ifaceDetail.PhysAddress)
 }
 
@@ -118,6 +159,86 @@ func (ifaceDetail *InterfaceDetail) ToJsonString() (string, error) {
 	return jsonString, nil
 }
 
+// OIDSet maps column OIDs, such as OIDIfName, to the instance OIDs requested for a single interface.
+// It lets callers look up the values of a GET by column rather than by the position of each varbind,
+// which shifts when the agent omits a varbind.
+type OIDSet map[string]string
+
+// InstanceOIDs returns the OIDs of the given columns for the interface with the given index.
+//
+// Example:
+//
+//	oids := interfaces.InstanceOIDs(3, interfaces.OIDIfName, interfaces.OIDIfHCInOctets)
+//	result, _, err := snmpClient.GetValue(oids.List())
+//	...
+//	values := oids.Values(result.Variables)
+//	name, ok := values[interfaces.OIDIfName].([]byte)
+func InstanceOIDs(index int, columns ...string) OIDSet {
+	set := make(OIDSet, len(columns))
+	for _, column := range columns {
+		set[column] = fmt.Sprintf("%s.%d", column, index)
+	}
+	return set
+}
+
+// List returns the instance OIDs of the set in a stable order, for use as the OIDs of a GET request.
+func (set OIDSet) List() []string {
+	oids := make([]string, 0, len(set))
+	for _, oid := range set {
+		oids = append(oids, oid)
+	}
+	sort.Strings(oids)
+	return oids
+}
+
+// Values matches the varbinds returned for the set by OID name and returns their values keyed by column OID.
+// Varbinds that are not part of the set, and those the agent reported as NoSuchObject, NoSuchInstance or
+// EndOfMibView, are left out, so a missing column is simply absent from the map.
+func (set OIDSet) Values(variables []gosnmp.SnmpPDU) map[string]interface{} {
+	columns := make(map[string]string, len(set))
+	for column, oid := range set {
+		columns[normalizeOID(oid)] = column
+	}
+
+	values := make(map[string]interface{}, len(set))
+	for _, variable := range variables {
+		switch variable.Type {
+		case gosnmp.NoSuchObject, gosnmp.NoSuchInstance, gosnmp.EndOfMibView, gosnmp.Null:
+			continue
+		}
+		if column, ok := columns[normalizeOID(variable.Name)]; ok {
+			values[column] = variable.Value
+		}
+	}
+	return values
+}
+
+// normalizeOID returns the OID with a single leading dot, as gosnmp returns OID names.
+func normalizeOID(oid string) string {
+	return "." + strings.TrimPrefix(oid, ".")
+}
+
+// Values of the SNMPv2-TC TruthValue and the IF-MIB ifAdminStatus/ifOperStatus enumerations.
+const (
+	TruthValueTrue  = 1
+	TruthValueFalse = 2
+
+	StatusUp             = 1
+	StatusDown           = 2
+	StatusTesting        = 3
+	StatusUnknown        = 4
+	StatusDormant        = 5
+	StatusNotPresent     = 6
+	StatusLowerLayerDown = 7
+)
+
+// Values of the EtherLike-MIB dot3StatsDuplexStatus enumeration.
+const (
+	DuplexUnknown = 1
+	DuplexHalf    = 2
+	DuplexFull    = 3
+)
+
 const (
 	OIDIfDescr                    = ".1.3.6.1.2.1.2.2.1.2"
 	OIDIfName                     = ".1.3.6.1.2.1.31.1.1.1.1"
@@ -156,4 +277,79 @@ const (
 	OIDIfPromiscuousMode          = ".1.3.6.1.2.1.31.1.1.1.16"
 	OIDIfConnectorPresent         = ".1.3.6.1.2.1.31.1.1.1.17"
 	OIDIfCounterDiscontinuityTime = ".1.3.6.1.2.1.31.1.1.1.19"
+	OIDDot3StatsDuplexStatus      = ".1.3.6.1.2.1.10.7.2.1.19"
 )
+
+// ifTypeNames maps the common IANAifType-MIB values to their names.
+var ifTypeNames = map[int]string{
+	1:   "other",
+	6:   "ethernetCsmacd",
+	23:  "ppp",
+	24:  "softwareLoopback",
+	32:  "frameRelay",
+	37:  "atm",
+	39:  "sonet",
+	49:  "aal5",
+	53:  "propVirtual",
+	71:  "ieee80211",
+	77:  "lapd",
+	81:  "ds0",
+	108: "pppMultilinkBundle",
+	117: "gigabitEthernet",
+	131: "tunnel",
+	134: "atmSubInterface",
+	135: "l2vlan",
+	136: "l3ipvlan",
+	142: "ipForward",
+	150: "mplsTunnel",
+	161: "ieee8023adLag",
+	166: "mpls",
+	209: "bridge",
+	244: "wwanPP2",
+	246: "ilan",
+	247: "pip",
+	249: "aluELP",
+	250: "gpon",
+	258: "vmwareVirtualNic",
+	259: "vmwareNicTeam",
+}
+
+// IfTypeName returns the IANAifType name of an ifType value, e.g. "ethernetCsmacd" for 6,
+// or "unknown(<n>)" for a value it does not know.
+func IfTypeName(t int) string {
+	if name, ok := ifTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", t)
+}
+
+// IfTypeByName returns the ifType value of an IANAifType name, e.g. 6 for "ethernetCsmacd".
+// It returns false for a name it does not know.
+func IfTypeByName(name string) (int, bool) {
+	for ifType, typeName := range ifTypeNames {
+		if typeName == name {
+			return ifType, true
+		}
+	}
+	return 0, false
+}
+
+// ifStatusNames maps the IF-MIB ifAdminStatus and ifOperStatus values to their names.
+var ifStatusNames = map[int]string{
+	StatusUp:             "up",
+	StatusDown:           "down",
+	StatusTesting:        "testing",
+	StatusUnknown:        "unknown",
+	StatusDormant:        "dormant",
+	StatusNotPresent:     "notPresent",
+	StatusLowerLayerDown: "lowerLayerDown",
+}
+
+// IfStatusName returns the name of an ifAdminStatus or ifOperStatus value, e.g. "down" for 2,
+// or "unknown(<n>)" for a value outside the enumeration.
+func IfStatusName(s int) string {
+	if name, ok := ifStatusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", s)
+}
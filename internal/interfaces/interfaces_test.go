@@ -0,0 +1,135 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package interfaces
+
+import (
+	"github.com/gosnmp/gosnmp"
+	"strings"
+	"testing"
+)
+
+func TestIfTypeName(t *testing.T) {
+	tests := []struct {
+		ifType int
+		want   string
+	}{
+		{1, "other"},
+		{6, "ethernetCsmacd"},
+		{24, "softwareLoopback"},
+		{53, "propVirtual"},
+		{131, "tunnel"},
+		{135, "l2vlan"},
+		{161, "ieee8023adLag"},
+		{0, "unknown(0)"},
+		{-1, "unknown(-1)"},
+		{100000, "unknown(100000)"},
+	}
+	for _, tt := range tests {
+		if got := IfTypeName(tt.ifType); got != tt.want {
+			t.Errorf("IfTypeName(%d) = %q, want %q", tt.ifType, got, tt.want)
+		}
+	}
+}
+
+func TestIfTypeByName(t *testing.T) {
+	for ifType, name := range ifTypeNames {
+		got, ok := IfTypeByName(name)
+		if !ok || got != ifType {
+			t.Errorf("IfTypeByName(%q) = %d, %t, want %d, true", name, got, ok, ifType)
+		}
+	}
+	if got, ok := IfTypeByName("notAnIfType"); ok {
+		t.Errorf("IfTypeByName(%q) = %d, true, want false", "notAnIfType", got)
+	}
+}
+
+func TestIfStatusName(t *testing.T) {
+	tests := []struct {
+		status int
+		want   string
+	}{
+		{StatusUp, "up"},
+		{StatusDown, "down"},
+		{StatusTesting, "testing"},
+		{StatusUnknown, "unknown"},
+		{StatusDormant, "dormant"},
+		{StatusNotPresent, "notPresent"},
+		{StatusLowerLayerDown, "lowerLayerDown"},
+		{0, "unknown(0)"},
+		{8, "unknown(8)"},
+	}
+	for _, tt := range tests {
+		if got := IfStatusName(tt.status); got != tt.want {
+			t.Errorf("IfStatusName(%d) = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestOIDSetValues(t *testing.T) {
+	oids := InstanceOIDs(3, OIDIfName, OIDIfInOctets, OIDIfHCInOctets)
+	tests := []struct {
+		name      string
+		variables []gosnmp.SnmpPDU
+		want      map[string]interface{}
+	}{
+		{"all present", []gosnmp.SnmpPDU{
+			{Name: oids[OIDIfName], Type: gosnmp.OctetString, Value: []byte("Gi0/3")},
+			{Name: oids[OIDIfInOctets], Type: gosnmp.Counter32, Value: uint(1000)},
+			{Name: oids[OIDIfHCInOctets], Type: gosnmp.Counter64, Value: uint64(1000)},
+		}, map[string]interface{}{OIDIfName: "Gi0/3", OIDIfInOctets: uint(1000), OIDIfHCInOctets: uint64(1000)}},
+		{"out of order without leading dots", []gosnmp.SnmpPDU{
+			{Name: strings.TrimPrefix(oids[OIDIfHCInOctets], "."), Type: gosnmp.Counter64, Value: uint64(1000)},
+			{Name: strings.TrimPrefix(oids[OIDIfName], "."), Type: gosnmp.OctetString, Value: []byte("Gi0/3")},
+		}, map[string]interface{}{OIDIfName: "Gi0/3", OIDIfHCInOctets: uint64(1000)}},
+		{"NoSuchObject", []gosnmp.SnmpPDU{
+			{Name: oids[OIDIfName], Type: gosnmp.OctetString, Value: []byte("Gi0/3")},
+			{Name: oids[OIDIfHCInOctets], Type: gosnmp.NoSuchObject},
+		}, map[string]interface{}{OIDIfName: "Gi0/3"}},
+		{"NoSuchInstance", []gosnmp.SnmpPDU{
+			{Name: oids[OIDIfName], Type: gosnmp.NoSuchInstance},
+			{Name: oids[OIDIfInOctets], Type: gosnmp.Counter32, Value: uint(1000)},
+		}, map[string]interface{}{OIDIfInOctets: uint(1000)}},
+		{"EndOfMibView", []gosnmp.SnmpPDU{
+			{Name: oids[OIDIfHCInOctets], Type: gosnmp.EndOfMibView},
+		}, map[string]interface{}{}},
+		{"Null", []gosnmp.SnmpPDU{
+			{Name: oids[OIDIfInOctets], Type: gosnmp.Null},
+			{Name: oids[OIDIfHCInOctets], Type: gosnmp.Counter64, Value: uint64(1000)},
+		}, map[string]interface{}{OIDIfHCInOctets: uint64(1000)}},
+		{"not part of the set", []gosnmp.SnmpPDU{
+			{Name: OIDIfName + ".4", Type: gosnmp.OctetString, Value: []byte("Gi0/4")},
+		}, map[string]interface{}{}},
+		{"empty response", nil, map[string]interface{}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := oids.Values(tt.variables)
+			if len(values) != len(tt.want) {
+				t.Errorf("Values returned %v, want %v", values, tt.want)
+			}
+			for column, want := range tt.want {
+				got, ok := values[column]
+				if bytes, isBytes := got.([]byte); isBytes {
+					got = string(bytes)
+				}
+				if !ok || got != want {
+					t.Errorf("value of column %s = %v, want %v", column, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package interfaces
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dmabry/gochecks/internal/clock"
+	"github.com/dmabry/gochecks/internal/snmp"
+	"github.com/dmabry/gochecks/internal/state"
+	"log/slog"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Names holds the identifying IF-MIB columns of a single interface.
+type Names struct {
+	Name  string `json:"name"`
+	Alias string `json:"alias"`
+	Type  int    `json:"type"`
+}
+
+// NameCache is the ifIndex to Names map of a target as saved on disk, along with the time it was walked.
+type NameCache struct {
+	Walked     time.Time     `json:"walked"`
+	Interfaces map[int]Names `json:"interfaces"`
+}
+
+// WalkNames walks ifName, ifAlias and ifType on the SNMP target over a single session and returns the
+// Names of every interface keyed by ifIndex.
+func WalkNames(snmpClient *snmp.Client) (map[int]Names, error) {
+	return snmp.WithSession(snmpClient, walkNames)
+}
+
+// walkNames walks the name columns over session for WalkNames.
+func walkNames(session *snmp.Session) (map[int]Names, error) {
+	names := make(map[int]Names)
+	for _, column := range []string{OIDIfName, OIDIfAlias, OIDIfType} {
+		result, _, err := session.Walk(column)
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk %s: %w", column, err)
+		}
+		for oid, value := range result {
+			rawIndex, err := snmp.TableIndex(column, oid)
+			if err != nil {
+				return nil, err
+			}
+			index, err := strconv.Atoi(rawIndex)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert interface index to int: %w", err)
+			}
+			entry := names[index]
+			switch column {
+			case OIDIfName:
+				entry.Name = snmp.ToString(value)
+			case OIDIfAlias:
+				entry.Alias = snmp.ToString(value)
+			case OIDIfType:
+				if ifType, ok := value.(int); ok {
+					entry.Type = ifType
+				}
+			}
+			names[index] = entry
+		}
+	}
+	return names, nil
+}
+
+// LoadNames returns the Names of every interface of the SNMP target keyed by ifIndex, read from the cache
+// file of the target in dir when it was walked less than ttl ago, and walked with WalkNames otherwise.
+// A fresh walk is saved to dir for the next run. With refresh set, the cache is ignored and replaced,
+// e.g. when a requested interface is missing from the cached map.
+//
+// Parameters:
+//   - snmpClient: The SNMP client used to walk the names.
+//   - dir: The directory holding the cache files, shared with the other state files.
+//   - ttl: How long a cached map is used before it is walked again.
+//   - refresh: Walk the names even when the cache is still fresh.
+//
+// Returns:
+//   - names: The Names of every interface keyed by ifIndex.
+//   - cached: Whether names was read from the cache rather than walked.
+//   - error: An error when the walk fails or the cache cannot be saved.
+//
+// Example:
+//
+//	names, _, err := interfaces.LoadNames(&snmpClient, "/var/lib/gochecks", time.Hour, false)
+func LoadNames(snmpClient *snmp.Client, dir string, ttl time.Duration, refresh bool) (map[int]Names, bool, error) {
+	key := "ifnames_" + snmpClient.Target
+	if !refresh {
+		var cache NameCache
+		found, err := state.Load(dir, key, &cache)
+		if err != nil {
+			slog.Debug("ignoring unreadable interface name cache", "target", snmpClient.Target, "error", err)
+		}
+		if found && err == nil && clock.Since(cache.Walked) < ttl {
+			return cache.Interfaces, true, nil
+		}
+	}
+
+	walked := clock.Now()
+	names, err := WalkNames(snmpClient)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := state.Save(dir, key, NameCache{Walked: walked, Interfaces: names}); err != nil {
+		return nil, false, fmt.Errorf("failed to save interface name cache: %w", err)
+	}
+	return names, false, nil
+}
+
+// FindInterfaceIndex walks the given IF-MIB text column, ifName or ifAlias, and returns the index of the
+// single interface whose value equals value. Interface indexes may change across reboots on some
+// platforms, while names and aliases are stable, so checks can be defined by name instead of index.
+//
+// Parameters:
+//   - snmpClient: The SNMP client used to walk the column.
+//   - column: The column to match, OIDIfName or OIDIfAlias.
+//   - value: The exact name or alias of the interface, e.g. "Gi0/1".
+//
+// Returns:
+//   - index: The current index of the interface.
+//   - error: An error when the walk fails or when no interface, or more than one, matches.
+func FindInterfaceIndex(snmpClient *snmp.Client, column string, value string) (int, error) {
+	result, _, err := snmpClient.Walk(column)
+	if err != nil {
+		return 0, fmt.Errorf("failed to walk %s: %w", column, err)
+	}
+
+	var indexes []int
+	for oid, val := range result {
+		if snmp.ToString(val) != value {
+			continue
+		}
+		rawIndex, err := snmp.TableIndex(column, oid)
+		if err != nil {
+			return 0, err
+		}
+		index, err := strconv.Atoi(rawIndex)
+		if err != nil {
+			return 0, fmt.Errorf("failed to convert interface index to int: %w", err)
+		}
+		indexes = append(indexes, index)
+	}
+	return singleIndex(indexes, value)
+}
+
+// errNoInterface is returned by singleIndex when no interface matches the requested name or alias.
+var errNoInterface = errors.New("no interface matches")
+
+// singleIndex returns the only index of indexes, the interfaces matching value, or an error when there is
+// none or more than one.
+func singleIndex(indexes []int, value string) (int, error) {
+	sort.Ints(indexes)
+	switch len(indexes) {
+	case 0:
+		return 0, fmt.Errorf("%w '%s'", errNoInterface, value)
+	case 1:
+		return indexes[0], nil
+	default:
+		return 0, fmt.Errorf("%d interfaces match '%s', with indexes %v", len(indexes), value, indexes)
+	}
+}
+
+// FindCachedInterfaceIndex is like FindInterfaceIndex, but matches value against the ifIndex to ifName,
+// ifAlias and ifType map of the target cached in cacheDir by LoadNames, so frequent runs do not
+// walk the names every time. The map is walked again once it is older than cacheTTL, or when no cached
+// interface matches value, since the interface may have been added or renamed since.
+//
+// Parameters:
+//   - snmpClient: The SNMP client used to walk the names when the cache is stale.
+//   - column: The column to match, OIDIfName or OIDIfAlias.
+//   - value: The exact name or alias of the interface, e.g. "Gi0/1".
+//   - cacheDir: The directory holding the cache files.
+//   - cacheTTL: How long the cached map is used before it is walked again.
+//
+// Returns:
+//   - index: The current index of the interface.
+//   - error: An error when the walk fails, the cache cannot be saved, or no interface, or more than one, matches.
+func FindCachedInterfaceIndex(snmpClient *snmp.Client, column string, value string, cacheDir string, cacheTTL time.Duration) (int, error) {
+	refresh := false
+	for {
+		names, cached, err := LoadNames(snmpClient, cacheDir, cacheTTL, refresh)
+		if err != nil {
+			return 0, err
+		}
+
+		var indexes []int
+		for index, entry := range names {
+			if (column == OIDIfName && entry.Name == value) || (column == OIDIfAlias && entry.Alias == value) {
+				indexes = append(indexes, index)
+			}
+		}
+		index, err := singleIndex(indexes, value)
+		if errors.Is(err, errNoInterface) && cached {
+			slog.Debug("interface missing from the name cache, walking the names again", "target", snmpClient.Target, "value", value)
+			refresh = true
+			continue
+		}
+		return index, err
+	}
+}
@@ -0,0 +1,69 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package interfaces
+
+import (
+	"github.com/dmabry/gochecks/internal/clock"
+	"github.com/dmabry/gochecks/internal/snmp"
+	"github.com/dmabry/gochecks/internal/snmp/snmptest"
+	"testing"
+	"time"
+)
+
+func TestLoadNamesCache(t *testing.T) {
+	previous := clock.Default
+	t.Cleanup(func() { clock.Default = previous })
+
+	agent := snmptest.NewAgent(t, map[string]interface{}{
+		OIDIfName + ".1":  "Gi0/1",
+		OIDIfAlias + ".1": "uplink",
+		OIDIfType + ".1":  6,
+	})
+	client := snmp.Client{Target: agent.Target, Community: "public", Timeout: time.Second, Retries: -1}
+	dir := t.TempDir()
+
+	tests := []struct {
+		name       string
+		elapsed    time.Duration
+		refresh    bool
+		wantCached bool
+	}{
+		{"first run walks", 0, false, false},
+		{"within ttl uses the cache", 59 * time.Minute, false, true},
+		{"refresh walks within ttl", 59 * time.Minute, true, false},
+		{"refreshed cache is used", 2 * time.Hour, false, true},
+		{"expired cache walks", 3 * time.Hour, false, false},
+	}
+	for _, tt := range tests {
+		clock.Default = clock.Fixed(testStart.Add(tt.elapsed))
+		requests := len(agent.Requests())
+
+		names, cached, err := LoadNames(&client, dir, time.Hour+30*time.Minute, tt.refresh)
+		if err != nil {
+			t.Fatalf("%s: LoadNames returned error: %v", tt.name, err)
+		}
+		if cached != tt.wantCached {
+			t.Errorf("%s: LoadNames returned cached %t, want %t", tt.name, cached, tt.wantCached)
+		}
+		if walked := len(agent.Requests()) > requests; walked == tt.wantCached {
+			t.Errorf("%s: LoadNames sent requests %t, want %t", tt.name, walked, !tt.wantCached)
+		}
+		if names[1] != (Names{Name: "Gi0/1", Alias: "uplink", Type: 6}) {
+			t.Errorf("%s: LoadNames returned %+v, want the names of Gi0/1", tt.name, names[1])
+		}
+	}
+}
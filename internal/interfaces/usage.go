@@ -0,0 +1,439 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package interfaces
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dmabry/gochecks/internal/clock"
+	"github.com/dmabry/gochecks/internal/graphite"
+	"github.com/dmabry/gochecks/internal/snmp"
+	"github.com/dmabry/gochecks/internal/threshold"
+	"github.com/dmabry/gomonitor"
+	"log/slog"
+	"time"
+)
+
+// InterfaceMetrics represents the metrics of a network interface.
+// All counters and speeds are stored as uint64 so the rate math behaves the same on 32-bit and
+// 64-bit builds, where uint may only be 32 bits wide.
+// LowCapacity is set when the agent does not implement the 64-bit ifHCInOctets and ifHCOutOctets, as on
+// older gear without the ifXTable, so the rates are computed from the 32-bit counters only. An aggregate is
+// LowCapacity only when none of its members implements them.
+// Members holds the metrics of each interface of an aggregate, whose own counters are left zero, since a sum
+// of counters cannot be told apart from a wrap of one of them. The deltas are computed per member and summed.
+type InterfaceMetrics struct {
+	Name        string
+	In          uint64
+	Out         uint64
+	HCIn        uint64
+	HCOut       uint64
+	Speed       uint64
+	HighSpeed   uint64
+	LowCapacity bool
+	Latency     time.Duration
+	Timestamp   time.Time
+	Members     []InterfaceMetrics `json:",omitempty"`
+}
+
+// convertToScale converts a given value to the appropriate scale (bps, Kbps, Mbps, or Gbps).
+// The function takes an input value in bits per second (bps) and returns the converted value
+// along with the corresponding unit of measurement.
+//
+// Parameters:
+//   - value: The input value in bits per second (bps) to be converted.
+//
+// Returns:
+//   - out: The converted value in the appropriate scale (bps, Kbps, Mbps, or Gbps).
+//   - unit: The corresponding unit of measurement for the converted value.
+func convertToScale(value uint64) (out uint64, unit string) {
+	bps := value * 8
+	if bps < 1000 {
+		return bps, "bps"
+	}
+
+	kbps := bps / 1000 // convert octets to Kbps
+	if kbps < 1000 {
+		return kbps, "Kbps"
+	}
+
+	mbps := kbps / 1000 // convert Kbps to Mbps
+	if mbps < 1000 {
+		return mbps, "Mbps"
+	}
+
+	gbps := mbps / 1000 // convert Mbps to Gbps
+	return gbps, "Gbps"
+}
+
+// uint64Value returns the counter or gauge value of column as a uint64, whatever its width, so a device
+// returning a Counter64 where a Counter32 is expected does not break the check. A missing value, e.g. an
+// ifHighSpeed the agent does not implement, is treated as 0. A value of any other type is logged at debug
+// level and treated as 0 as well.
+func uint64Value(values map[string]interface{}, oids OIDSet, column string) uint64 {
+	value, ok := values[column]
+	if !ok {
+		return 0
+	}
+	switch val := value.(type) {
+	case uint:
+		return uint64(val)
+	case uint32:
+		return uint64(val)
+	case uint64:
+		return val
+	case int:
+		if val >= 0 {
+			return uint64(val)
+		}
+	}
+	slog.Debug("Unexpected value type", "oid", oids[column], "expected", "uint", "type", fmt.Sprintf("%T", value), "value", value)
+	return 0
+}
+
+// GetInterfaceMetrics retrieves the network interface metrics for a specific interface
+// using the provided SNMP client and index.
+//
+// Parameters:
+//   - snmpClient: The SNMP client used to connect and retrieve the metrics.
+//   - index: The index of the interface to retrieve the metrics for.
+//
+// Returns:
+//   - metrics: The network interface metrics for the specified interface.
+//   - error: Any error encountered during the retrieval of the metrics.
+func GetInterfaceMetrics(snmpClient *snmp.Client, index int) (*InterfaceMetrics, error) {
+	oids := InstanceOIDs(index, OIDIfName, OIDIfDescr, OIDIfInOctets, OIDIfOutOctets,
+		OIDIfHCInOctets, OIDIfHCOutOctets, OIDIfSpeed, OIDIfHighSpeed)
+
+	result, latency, err := snmpClient.GetValue(oids.List())
+	if err != nil {
+		eMessage := fmt.Sprintf("Requested OID: %s", err)
+		return nil, fmt.Errorf("%s: %w", eMessage, err)
+	}
+
+	values := oids.Values(result.Variables)
+	// ifName is part of the ifXTable, so fall back to ifDescr on agents that do not implement it
+	nameColumn := OIDIfName
+	if _, ok := values[OIDIfName]; !ok {
+		nameColumn = OIDIfDescr
+	}
+	if _, ok := values[nameColumn]; !ok {
+		eMessage := fmt.Sprintf("Index doesn't exist?")
+		return nil, fmt.Errorf("%s", eMessage)
+	}
+	name, ok := values[nameColumn].([]byte)
+	if !ok {
+		value := values[nameColumn]
+		return nil, fmt.Errorf("value for OID %s is not of type []byte: %T -> %v", oids[nameColumn], value, value)
+	}
+	_, hasHCIn := values[OIDIfHCInOctets]
+	_, hasHCOut := values[OIDIfHCOutOctets]
+
+	metrics := &InterfaceMetrics{
+		Name:        string(name),
+		In:          uint64Value(values, oids, OIDIfInOctets),
+		Out:         uint64Value(values, oids, OIDIfOutOctets),
+		HCIn:        uint64Value(values, oids, OIDIfHCInOctets),
+		HCOut:       uint64Value(values, oids, OIDIfHCOutOctets),
+		Speed:       uint64Value(values, oids, OIDIfSpeed),
+		HighSpeed:   uint64Value(values, oids, OIDIfHighSpeed),
+		LowCapacity: !hasHCIn || !hasHCOut,
+		Latency:     latency,
+		Timestamp:   clock.Now(),
+	}
+
+	return metrics, nil
+}
+
+// saturatedSpeed is the value ifSpeed reports for links faster than its 32-bit gauge can hold.
+// minPeriod is the shortest interval between measurements a rate can be computed over.
+const (
+	saturatedSpeed = 4294967295
+	minPeriod      = time.Second
+)
+
+// linkSpeed returns the link speed of the interface in bps. ifSpeed is used unless it is saturated
+// or zero, in which case ifHighSpeed, reported in Mbps, is used instead. It returns 0 when neither is known.
+func linkSpeed(metrics InterfaceMetrics) uint64 {
+	if metrics.Speed == 0 || metrics.Speed >= saturatedSpeed {
+		return metrics.HighSpeed * 1000000
+	}
+	return metrics.Speed
+}
+
+// counterDelta returns the increase of a counter of the given width in bits (32 or 64) between two samples.
+// A 32-bit counter that went backwards is assumed to have wrapped once, which happens within seconds on
+// fast links. A 64-bit counter practically never wraps, so a decrease means the counter was reset, for
+// example by a reboot, and ok is false.
+func counterDelta(first uint64, second uint64, width int) (delta uint64, ok bool) {
+	if second >= first {
+		return second - first, true
+	}
+	if width == 32 {
+		return (1<<32 - first) + second, true
+	}
+	return 0, false
+}
+
+// errCounterReset is returned by octetDeltas when a 64-bit counter went backwards between two measurements.
+var errCounterReset = errors.New("counters went backwards between measurements")
+
+// octetDeltas holds the increase of the octet counters of an interface, or of an aggregate, between two measurements.
+type octetDeltas struct {
+	in    uint64
+	out   uint64
+	hcIn  uint64
+	hcOut uint64
+}
+
+// add adds the deltas of other to d.
+func (d *octetDeltas) add(other octetDeltas) {
+	d.in += other.in
+	d.out += other.out
+	d.hcIn += other.hcIn
+	d.hcOut += other.hcOut
+}
+
+// counterDeltas returns the increase of the octet counters between two measurements. When either measurement is
+// LowCapacity, the 64-bit deltas fall back to the 32-bit ones. For an aggregate, the deltas of each member are
+// computed on their own, falling back per member, and summed, so a single member without 64-bit counters does
+// not push the whole aggregate onto the 32-bit counters. An error is returned when the members differ between
+// the measurements, and errCounterReset when a 64-bit counter went backwards.
+func counterDeltas(first InterfaceMetrics, second InterfaceMetrics) (octetDeltas, error) {
+	if len(first.Members) > 0 || len(second.Members) > 0 {
+		if len(first.Members) != len(second.Members) {
+			return octetDeltas{}, fmt.Errorf("the members of %s changed between measurements", second.Name)
+		}
+		var total octetDeltas
+		for i := range second.Members {
+			if first.Members[i].Name != second.Members[i].Name {
+				return octetDeltas{}, fmt.Errorf("the members of %s changed between measurements", second.Name)
+			}
+			deltas, err := counterDeltas(first.Members[i], second.Members[i])
+			if err != nil {
+				return octetDeltas{}, err
+			}
+			total.add(deltas)
+		}
+		return total, nil
+	}
+
+	var deltas octetDeltas
+	deltas.in, _ = counterDelta(first.In, second.In, 32)
+	deltas.out, _ = counterDelta(first.Out, second.Out, 32)
+	if first.LowCapacity || second.LowCapacity {
+		deltas.hcIn, deltas.hcOut = deltas.in, deltas.out
+		return deltas, nil
+	}
+	var hcInOK, hcOutOK bool
+	deltas.hcIn, hcInOK = counterDelta(first.HCIn, second.HCIn, 64)
+	deltas.hcOut, hcOutOK = counterDelta(first.HCOut, second.HCOut, 64)
+	if !hcInOK || !hcOutOK {
+		return octetDeltas{}, errCounterReset
+	}
+	return deltas, nil
+}
+
+// DetermineInterfaceUsage calculates the usage of a network interface based on the provided InterfaceMetrics.
+// It compares the metrics between two time periods and determines if the inbound and outbound traffic exceeds
+// the given warning and critical thresholds. It also converts the traffic values to the appropriate scale (bps,
+// Kbps, Mbps, or Gbps) and crafts a message with the results.
+// Wrapped 32-bit counters are accounted for, while a decrease of the 64-bit counters means the device or
+// interface was reset between the measurements, so an Unknown result is returned instead of a bogus rate.
+// When either measurement is LowCapacity, the rates are computed from the 32-bit counters alone, the
+// 64-bit rates are left out of the message and performance data, and the message says so.
+//
+// Parameters:
+//   - first: The InterfaceMetrics representing the metrics of the first time period.
+//   - second: The InterfaceMetrics representing the metrics of the second time period.
+//   - warnIn: The warning range for inbound traffic in bps, or in percent of the link speed. nil disables it.
+//   - warnOut: The warning range for outbound traffic in bps, or in percent of the link speed. nil disables it.
+//   - critIn: The critical range for inbound traffic in bps, or in percent of the link speed. nil disables it.
+//   - critOut: The critical range for outbound traffic in bps, or in percent of the link speed. nil disables it.
+//     The ranges use the Nagios threshold format, so a bare number alerts when the traffic exceeds it.
+//   - percent: A boolean indicating whether the thresholds are percentages of the link speed. An Unknown
+//     result is returned when the interface reports a link speed of 0.
+//   - enablePerf: A boolean indicating whether to include performance data in the check result.
+//     The utilization in percent of the link speed is included whenever the link speed is known.
+//     The maximum of the bps metrics is the link speed, taken from ifHighSpeed when ifSpeed is saturated or zero.
+//   - minInterval: The minimum time between the two measurements for a rate to be reported. Agents that
+//     only refresh their counters every few seconds yield zero-then-spike rates over shorter intervals,
+//     so an Unknown result is returned instead. Intervals below one second always return Unknown.
+//
+// Returns:
+//   - checkResult: A pointer to a gomonitor.CheckResult object that represents the result of the interface usage calculation.
+//
+// Example:
+//
+//	first := interfaces.InterfaceMetrics{Name: "eth0", In: 100, Out: 200, HCIn: 300, HCOut: 400, Speed: 1000, Latency: 10 * time.Millisecond, Timestamp: time.Now()}
+//	second := interfaces.InterfaceMetrics{Name: "eth0", In: 200, Out: 300, HCIn: 400, HCOut: 500, Speed: 1000, Latency: 20 * time.Millisecond, Timestamp: time.Now()}
+//	warn, _ := threshold.Parse("500")
+//	crit, _ := threshold.Parse("1000")
+//	result := interfaces.DetermineInterfaceUsage(first, second, &warn, &warn, &crit, &crit, false, true, 5*time.Second)
+//	result.SendResult()
+func DetermineInterfaceUsage(first InterfaceMetrics, second InterfaceMetrics, warnIn *threshold.Range, warnOut *threshold.Range, critIn *threshold.Range, critOut *threshold.Range, percent bool, enablePerf bool, minInterval time.Duration) *gomonitor.CheckResult {
+	checkResult := gomonitor.NewCheckResult()
+	intName := first.Name
+	periodDiff := second.Timestamp.Sub(first.Timestamp)
+	// Rates are computed per whole second, so never allow an interval below one second
+	minInterval = max(minInterval, minPeriod)
+	if periodDiff < minInterval {
+		eMessage := fmt.Sprintf("%s - Interval between measurements %s is shorter than the minimum %s", intName, periodDiff, minInterval)
+		checkResult.SetResult(gomonitor.Unknown, eMessage)
+		return checkResult
+	}
+	period := periodDiff.Seconds()
+	avgLatency := (first.Latency + second.Latency) / 2
+	// Calc deltas, skipping the sample when the counters were reset between measurements
+	lowCapacity := first.LowCapacity || second.LowCapacity
+	deltas, err := counterDeltas(first, second)
+	if errors.Is(err, errCounterReset) {
+		eMessage := fmt.Sprintf("%s - Counters went backwards between measurements, the device or interface was likely reset. Skipping this sample", intName)
+		checkResult.SetResult(gomonitor.Unknown, eMessage)
+		return checkResult
+	}
+	if err != nil {
+		checkResult.SetResult(gomonitor.Unknown, fmt.Sprintf("%s - %s. Skipping this sample", intName, err))
+		return checkResult
+	}
+	// Calc rates
+	in := deltas.in / uint64(period)
+	out := deltas.out / uint64(period)
+	hcIn := deltas.hcIn / uint64(period)
+	hcOut := deltas.hcOut / uint64(period)
+	// Convert to scale
+	intIn, intInUnit := convertToScale(in)
+	intOut, intOutUnit := convertToScale(out)
+	intHCIn, intHCInUnit := convertToScale(hcIn)
+	intHCOut, intHCOutUnit := convertToScale(hcOut)
+	// Calc utilization against the link speed, preferring the 64-bit rates when available
+	speed := linkSpeed(first)
+	if percent && speed == 0 {
+		eMessage := fmt.Sprintf("%s - Link speed is reported as 0, cannot compute percent utilization", intName)
+		checkResult.SetResult(gomonitor.Unknown, eMessage)
+		return checkResult
+	}
+	var inPct, outPct float64
+	if speed > 0 {
+		inPct = float64(max(in, hcIn)*8) * 100 / float64(speed)
+		outPct = float64(max(out, hcOut)*8) * 100 / float64(speed)
+	}
+	// Craft message
+	message := fmt.Sprintf("%s - In: %d %s Out: %d %s HCIn: %d %s HCOut: %d %s", intName, intIn, intInUnit, intOut, intOutUnit, intHCIn, intHCInUnit, intHCOut, intHCOutUnit)
+	if lowCapacity {
+		message = fmt.Sprintf("%s - In: %d %s Out: %d %s (32-bit counters, 64-bit counters not available)", intName, intIn, intInUnit, intOut, intOutUnit)
+	}
+	if speed > 0 {
+		message += fmt.Sprintf(" Utilization In: %.2f%% Out: %.2f%%", inPct, outPct)
+	}
+	if enablePerf {
+		checkResult.AddPerformanceData("snmp_latency", gomonitor.PerformanceMetric{Value: avgLatency.Seconds(), UnitOM: "s"})
+		var bpsWarnIn, bpsCritIn, bpsWarnOut, bpsCritOut float64
+		if !percent {
+			bpsWarnIn, bpsCritIn = rangeBound(warnIn), rangeBound(critIn)
+			bpsWarnOut, bpsCritOut = rangeBound(warnOut), rangeBound(critOut)
+		}
+		checkResult.AddPerformanceData("in", gomonitor.PerformanceMetric{Value: float64(in * 8), Warn: bpsWarnIn, Crit: bpsCritIn, Min: 0, Max: float64(speed), UnitOM: "bps"})
+		checkResult.AddPerformanceData("out", gomonitor.PerformanceMetric{Value: float64(out * 8), Warn: bpsWarnOut, Crit: bpsCritOut, Min: 0, Max: float64(speed), UnitOM: "bps"})
+		if !lowCapacity {
+			checkResult.AddPerformanceData("hc_in", gomonitor.PerformanceMetric{Value: float64(hcIn * 8), Warn: bpsWarnIn, Crit: bpsCritIn, Min: 0, Max: float64(speed), UnitOM: "bps"})
+			checkResult.AddPerformanceData("hc_out", gomonitor.PerformanceMetric{Value: float64(hcOut * 8), Warn: bpsWarnOut, Crit: bpsCritOut, Min: 0, Max: float64(speed), UnitOM: "bps"})
+		}
+		if speed > 0 {
+			pctIn := gomonitor.PerformanceMetric{Value: inPct, Min: 0, Max: 100, UnitOM: "%"}
+			pctOut := gomonitor.PerformanceMetric{Value: outPct, Min: 0, Max: 100, UnitOM: "%"}
+			if percent {
+				pctIn.Warn, pctIn.Crit = rangeBound(warnIn), rangeBound(critIn)
+				pctOut.Warn, pctOut.Crit = rangeBound(warnOut), rangeBound(critOut)
+			}
+			checkResult.AddPerformanceData("in_pct", pctIn)
+			checkResult.AddPerformanceData("out_pct", pctOut)
+		}
+	}
+
+	// Evaluate the thresholds against the utilization, or against the bps rate preferring the 64-bit counters
+	inValue, outValue := inPct, outPct
+	if !percent {
+		inValue, outValue = float64(max(in, hcIn)*8), float64(max(out, hcOut)*8)
+	}
+	inCrit, inWarn := rangeBreached(critIn, inValue), rangeBreached(warnIn, inValue)
+	outCrit, outWarn := rangeBreached(critOut, outValue), rangeBreached(warnOut, outValue)
+
+	if inCrit {
+		checkResult.SetResult(gomonitor.Critical, "Inbound exceeds threshold "+message)
+	} else if inWarn {
+		checkResult.SetResult(gomonitor.Warning, "Inbound exceeds threshold "+message)
+	} else if outCrit {
+		checkResult.SetResult(gomonitor.Critical, "Outbound exceeds threshold "+message)
+	} else if outWarn {
+		checkResult.SetResult(gomonitor.Warning, "Outbound exceeds threshold "+message)
+	} else {
+		checkResult.SetResult(gomonitor.OK, message)
+	}
+	return checkResult
+}
+
+// GraphiteMetrics computes the inbound and outbound rates in bps between two measurements, preferring the
+// 64-bit counters unless either measurement is LowCapacity, and returns them as Graphite metrics named prefix.target.ifname.in_bps and
+// prefix.target.ifname.out_bps, timestamped with the second measurement. Each node of the path is
+// sanitized, so an interface name like "Gi0/1.100" becomes "Gi0_1_100".
+//
+// Parameters:
+//   - prefix: The leading nodes of the metric path, e.g. "network". May be empty.
+//   - target: The SNMP target the metrics were collected from.
+//   - first: The InterfaceMetrics representing the metrics of the first time period.
+//   - second: The InterfaceMetrics representing the metrics of the second time period.
+//
+// Returns:
+//   - metrics: The in_bps and out_bps metrics.
+//   - error: An error when the interval is too short, the 64-bit counters were reset or the members of an aggregate
+//     changed between the measurements.
+//
+// Example:
+//
+//	metrics, err := interfaces.GraphiteMetrics("network", "192.0.2.1", *measure1, *measure2)
+//	// network.192_0_2_1.eth0.in_bps 8000 1718000000
+func GraphiteMetrics(prefix string, target string, first InterfaceMetrics, second InterfaceMetrics) ([]graphite.Metric, error) {
+	period := uint64(second.Timestamp.Sub(first.Timestamp) / time.Second)
+	if period == 0 {
+		return nil, fmt.Errorf("interval between measurements is shorter than %s", minPeriod)
+	}
+	deltas, err := counterDeltas(first, second)
+	if err != nil {
+		return nil, err
+	}
+	in := max(deltas.in, deltas.hcIn) / period * 8
+	out := max(deltas.out, deltas.hcOut) / period * 8
+
+	return []graphite.Metric{
+		{Path: graphite.Path(prefix, target, first.Name, "in_bps"), Value: float64(in), Timestamp: second.Timestamp},
+		{Path: graphite.Path(prefix, target, first.Name, "out_bps"), Value: float64(out), Timestamp: second.Timestamp},
+	}, nil
+}
+
+// rangeBreached reports whether value breaches r, where a nil range is never breached.
+func rangeBreached(r *threshold.Range, value float64) bool {
+	return r != nil && r.Breaches(value)
+}
+
+// rangeBound returns the performance data level of r, or 0 when r is nil.
+func rangeBound(r *threshold.Range) float64 {
+	if r == nil {
+		return 0
+	}
+	return r.Bound()
+}
@@ -0,0 +1,367 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package interfaces
+
+import (
+	"github.com/dmabry/gochecks/internal/snmp"
+	"github.com/dmabry/gochecks/internal/snmp/snmptest"
+	"github.com/dmabry/gochecks/internal/threshold"
+	"github.com/dmabry/gomonitor"
+	"testing"
+	"time"
+)
+
+// testStart is the timestamp of the first measurement of the tests.
+var testStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// aggregate returns an aggregate of members named name, measured at timestamp.
+func aggregate(name string, timestamp time.Time, members ...InterfaceMetrics) InterfaceMetrics {
+	metrics := InterfaceMetrics{Name: name, LowCapacity: true, Timestamp: timestamp, Members: members}
+	for _, member := range members {
+		metrics.Speed += member.Speed
+		metrics.LowCapacity = metrics.LowCapacity && member.LowCapacity
+	}
+	return metrics
+}
+
+func TestDetermineInterfaceUsageAggregate(t *testing.T) {
+	// The 32-bit counters of the members sum beyond 2^32, and the first member wraps
+	first := aggregate("ISP-A", testStart,
+		InterfaceMetrics{Name: "Gi0/1", In: 4294967000, Out: 100, Speed: 1000000000, LowCapacity: true},
+		InterfaceMetrics{Name: "Gi0/2", In: 3000000000, Out: 100, Speed: 1000000000, LowCapacity: true},
+	)
+	second := aggregate("ISP-A", testStart.Add(time.Second),
+		InterfaceMetrics{Name: "Gi0/1", In: 296, Out: 200, Speed: 1000000000, LowCapacity: true},
+		InterfaceMetrics{Name: "Gi0/2", In: 3000001000, Out: 300, Speed: 1000000000, LowCapacity: true},
+	)
+
+	result := DetermineInterfaceUsage(first, second, nil, nil, nil, nil, false, true, 0)
+	if result.ExitCode != gomonitor.OK {
+		t.Fatalf("DetermineInterfaceUsage returned %s (%s), want OK", result.ExitCode, result.Message)
+	}
+	// (2^32 - 4294967000 + 296) + 1000 octets in, 100 + 200 octets out, over one second
+	if in := result.PerformanceData["in"].Value; in != 1592*8 {
+		t.Errorf("in = %v bps, want %v", in, 1592*8)
+	}
+	if out := result.PerformanceData["out"].Value; out != 300*8 {
+		t.Errorf("out = %v bps, want %v", out, 300*8)
+	}
+	if max := result.PerformanceData["in"].Max; max != 2000000000 {
+		t.Errorf("in max = %v, want the summed link speed 2000000000", max)
+	}
+}
+
+func TestDetermineInterfaceUsageAggregateMixedCapacity(t *testing.T) {
+	// Gi0/1 has 64-bit counters, Gi0/2 only 32-bit ones
+	first := aggregate("ISP-A", testStart,
+		InterfaceMetrics{Name: "Gi0/1", In: 1000, HCIn: 10000000000, Speed: 1000000000},
+		InterfaceMetrics{Name: "Gi0/2", In: 4294967000, Speed: 1000000000, LowCapacity: true},
+	)
+	second := aggregate("ISP-A", testStart.Add(time.Second),
+		InterfaceMetrics{Name: "Gi0/1", In: 2000, HCIn: 10000005000, Speed: 1000000000},
+		InterfaceMetrics{Name: "Gi0/2", In: 704, Speed: 1000000000, LowCapacity: true},
+	)
+	if second.LowCapacity {
+		t.Fatal("an aggregate with a 64-bit member must not be LowCapacity")
+	}
+
+	result := DetermineInterfaceUsage(first, second, nil, nil, nil, nil, false, true, 0)
+	if result.ExitCode != gomonitor.OK {
+		t.Fatalf("DetermineInterfaceUsage returned %s (%s), want OK", result.ExitCode, result.Message)
+	}
+	// 5000 octets from the 64-bit counter of Gi0/1 and 1000 from the wrapped 32-bit counter of Gi0/2
+	hcIn, ok := result.PerformanceData["hc_in"]
+	if !ok {
+		t.Fatal("hc_in performance data is missing")
+	}
+	if hcIn.Value != 6000*8 {
+		t.Errorf("hc_in = %v bps, want %v", hcIn.Value, 6000*8)
+	}
+}
+
+func TestDetermineInterfaceUsageAggregateMembersChanged(t *testing.T) {
+	first := aggregate("ISP-A", testStart, InterfaceMetrics{Name: "Gi0/1"})
+	second := aggregate("ISP-A", testStart.Add(time.Second), InterfaceMetrics{Name: "Gi0/1"}, InterfaceMetrics{Name: "Gi0/2"})
+
+	result := DetermineInterfaceUsage(first, second, nil, nil, nil, nil, false, false, 0)
+	if result.ExitCode != gomonitor.Unknown {
+		t.Errorf("DetermineInterfaceUsage returned %s (%s), want Unknown", result.ExitCode, result.Message)
+	}
+}
+
+func TestUint64Value(t *testing.T) {
+	oids := InstanceOIDs(1, OIDIfInOctets)
+	tests := []struct {
+		name  string
+		value interface{}
+		want  uint64
+	}{
+		{"Counter32 max", uint32(4294967295), 4294967295},
+		{"Gauge32", uint(4294967295), 4294967295},
+		{"Counter64", uint64(18446744073709551615), 18446744073709551615},
+		{"Integer", 42, 42},
+		{"negative Integer", -1, 0},
+		{"string", "42", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := map[string]interface{}{OIDIfInOctets: tt.value}
+			if got := uint64Value(values, oids, OIDIfInOctets); got != tt.want {
+				t.Errorf("uint64Value(%v) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+	if got := uint64Value(map[string]interface{}{}, oids, OIDIfInOctets); got != 0 {
+		t.Errorf("uint64Value of a missing column = %d, want 0", got)
+	}
+}
+
+func TestGetInterfaceMetricsLargeCounters(t *testing.T) {
+	oids := InstanceOIDs(3, OIDIfName, OIDIfInOctets, OIDIfOutOctets, OIDIfHCInOctets, OIDIfHCOutOctets, OIDIfSpeed, OIDIfHighSpeed)
+	agent := snmptest.NewAgent(t, map[string]interface{}{
+		oids[OIDIfName]:        "Te1/1",
+		oids[OIDIfInOctets]:    uint32(4294967295),
+		oids[OIDIfOutOctets]:   uint32(4294967000),
+		oids[OIDIfHCInOctets]:  uint64(1 << 40),
+		oids[OIDIfHCOutOctets]: uint64(18446744073709551615),
+		oids[OIDIfSpeed]:       uint(4294967295),
+		oids[OIDIfHighSpeed]:   uint(10000),
+	})
+	client := snmp.Client{Target: agent.Target, Community: "public", Timeout: time.Second, Retries: -1}
+
+	metrics, err := GetInterfaceMetrics(&client, 3)
+	if err != nil {
+		t.Fatalf("GetInterfaceMetrics returned error: %v", err)
+	}
+	want := InterfaceMetrics{Name: "Te1/1", In: 4294967295, Out: 4294967000, HCIn: 1 << 40,
+		HCOut: 18446744073709551615, Speed: 4294967295, HighSpeed: 10000}
+	if metrics.Name != want.Name || metrics.In != want.In || metrics.Out != want.Out || metrics.HCIn != want.HCIn ||
+		metrics.HCOut != want.HCOut || metrics.Speed != want.Speed || metrics.HighSpeed != want.HighSpeed {
+		t.Errorf("GetInterfaceMetrics = %+v, want %+v", *metrics, want)
+	}
+	if metrics.LowCapacity {
+		t.Error("LowCapacity is set for an interface with 64-bit counters")
+	}
+}
+
+func TestDetermineInterfaceUsageNearCounterMax(t *testing.T) {
+	first := InterfaceMetrics{Name: "Gi0/1", In: 4294967000, Out: 4294967290, Speed: 1000000000, LowCapacity: true, Timestamp: testStart}
+	second := InterfaceMetrics{Name: "Gi0/1", In: 4294967295, Out: 4294967295, Speed: 1000000000, LowCapacity: true,
+		Timestamp: testStart.Add(time.Second)}
+
+	result := DetermineInterfaceUsage(first, second, nil, nil, nil, nil, false, true, 0)
+	if result.ExitCode != gomonitor.OK {
+		t.Fatalf("DetermineInterfaceUsage returned %s (%s), want OK", result.ExitCode, result.Message)
+	}
+	if in := result.PerformanceData["in"].Value; in != 295*8 {
+		t.Errorf("in = %v bps, want %v", in, 295*8)
+	}
+	if out := result.PerformanceData["out"].Value; out != 5*8 {
+		t.Errorf("out = %v bps, want %v", out, 5*8)
+	}
+}
+
+func TestDetermineInterfaceUsageMinInterval(t *testing.T) {
+	tests := []struct {
+		name        string
+		elapsed     time.Duration
+		minInterval time.Duration
+		want        gomonitor.ExitCode
+	}{
+		{"shorter than minInterval", 4 * time.Second, 5 * time.Second, gomonitor.Unknown},
+		{"equal to minInterval", 5 * time.Second, 5 * time.Second, gomonitor.OK},
+		{"longer than minInterval", 30 * time.Second, 5 * time.Second, gomonitor.OK},
+		{"no minInterval", 2 * time.Second, 0, gomonitor.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := InterfaceMetrics{Name: "Gi0/1", HCIn: 1000, HCOut: 1000, Speed: 1000000000, Timestamp: testStart}
+			second := InterfaceMetrics{Name: "Gi0/1", HCIn: 2000, HCOut: 2000, Speed: 1000000000, Timestamp: testStart.Add(tt.elapsed)}
+			result := DetermineInterfaceUsage(first, second, nil, nil, nil, nil, false, false, tt.minInterval)
+			if result.ExitCode != tt.want {
+				t.Errorf("DetermineInterfaceUsage returned %s (%s), want %s", result.ExitCode, result.Message, tt.want)
+			}
+		})
+	}
+}
+
+func TestCounterDelta(t *testing.T) {
+	tests := []struct {
+		name   string
+		first  uint64
+		second uint64
+		width  int
+		want   uint64
+		wantOK bool
+	}{
+		{"32-bit increase", 100, 350, 32, 250, true},
+		{"32-bit unchanged", 100, 100, 32, 0, true},
+		{"32-bit wrap", 4294967000, 296, 32, 592, true},
+		{"32-bit wrap from max", 4294967295, 0, 32, 1, true},
+		{"64-bit increase", 1 << 40, 1<<40 + 5000, 64, 5000, true},
+		{"64-bit near max", 18446744073709551000, 18446744073709551615, 64, 615, true},
+		{"64-bit reset", 1 << 40, 1000, 64, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delta, ok := counterDelta(tt.first, tt.second, tt.width)
+			if delta != tt.want || ok != tt.wantOK {
+				t.Errorf("counterDelta(%d, %d, %d) = %d, %t, want %d, %t", tt.first, tt.second, tt.width, delta, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestDetermineInterfaceUsageCounterReset(t *testing.T) {
+	first := InterfaceMetrics{Name: "Te1/1", In: 5000, Out: 5000, HCIn: 1 << 40, HCOut: 1 << 40, Speed: 4294967295, HighSpeed: 10000, Timestamp: testStart}
+	second := InterfaceMetrics{Name: "Te1/1", In: 1000, Out: 1000, HCIn: 1000, HCOut: 1000, Speed: 4294967295, HighSpeed: 10000,
+		Timestamp: testStart.Add(time.Minute)}
+
+	result := DetermineInterfaceUsage(first, second, nil, nil, nil, nil, false, true, 0)
+	if result.ExitCode != gomonitor.Unknown {
+		t.Errorf("DetermineInterfaceUsage returned %s (%s) for reset 64-bit counters, want Unknown", result.ExitCode, result.Message)
+	}
+}
+
+func TestDetermineInterfaceUsageWrap(t *testing.T) {
+	// The 32-bit counters wrap, while the 64-bit counters keep counting
+	first := InterfaceMetrics{Name: "Te1/1", In: 4294967000, Out: 4294967000, HCIn: 1<<32 - 296, HCOut: 1<<32 - 296,
+		Speed: 4294967295, HighSpeed: 10000, Timestamp: testStart}
+	second := InterfaceMetrics{Name: "Te1/1", In: 9704, Out: 704, HCIn: 1<<32 + 9704, HCOut: 1<<32 + 704,
+		Speed: 4294967295, HighSpeed: 10000, Timestamp: testStart.Add(10 * time.Second)}
+
+	result := DetermineInterfaceUsage(first, second, nil, nil, nil, nil, false, true, 0)
+	if result.ExitCode != gomonitor.OK {
+		t.Fatalf("DetermineInterfaceUsage returned %s (%s), want OK", result.ExitCode, result.Message)
+	}
+	for _, metric := range []struct {
+		name string
+		want float64
+	}{
+		{"in", 1000 * 8}, {"out", 100 * 8}, {"hc_in", 1000 * 8}, {"hc_out", 100 * 8},
+	} {
+		if value := result.PerformanceData[metric.name].Value; value != metric.want {
+			t.Errorf("%s = %v bps, want %v", metric.name, value, metric.want)
+		}
+	}
+}
+
+func TestDetermineInterfaceUsageShortInterval(t *testing.T) {
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+	}{
+		{"identical timestamps", 0},
+		{"sub-second interval", 999 * time.Millisecond},
+		{"clock went backwards", -time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := InterfaceMetrics{Name: "Gi0/1", In: 1000, Out: 1000, Speed: 1000000000, LowCapacity: true, Timestamp: testStart}
+			second := InterfaceMetrics{Name: "Gi0/1", In: 2000, Out: 2000, Speed: 1000000000, LowCapacity: true, Timestamp: testStart.Add(tt.elapsed)}
+			warn, err := threshold.Parse("50")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result := DetermineInterfaceUsage(first, second, &warn, &warn, &warn, &warn, true, true, 0)
+			if result.ExitCode != gomonitor.Unknown {
+				t.Errorf("DetermineInterfaceUsage returned %s (%s), want Unknown", result.ExitCode, result.Message)
+			}
+			if len(result.PerformanceData) != 0 {
+				t.Errorf("DetermineInterfaceUsage returned performance data %v for an unusable interval", result.PerformanceData)
+			}
+		})
+	}
+}
+
+// interfaceValues returns the values of a 64-bit capable interface with index 3 and their instance OIDs.
+func interfaceValues() (map[string]interface{}, OIDSet) {
+	oids := InstanceOIDs(3, OIDIfName, OIDIfDescr, OIDIfInOctets, OIDIfOutOctets, OIDIfHCInOctets, OIDIfHCOutOctets, OIDIfSpeed, OIDIfHighSpeed)
+	return map[string]interface{}{
+		oids[OIDIfName]:        "Gi0/3",
+		oids[OIDIfDescr]:       "GigabitEthernet0/3",
+		oids[OIDIfInOctets]:    uint32(1000),
+		oids[OIDIfOutOctets]:   uint32(2000),
+		oids[OIDIfHCInOctets]:  uint64(1000),
+		oids[OIDIfHCOutOctets]: uint64(2000),
+		oids[OIDIfSpeed]:       uint(1000000000),
+		oids[OIDIfHighSpeed]:   uint(1000),
+	}, oids
+}
+
+func TestGetInterfaceMetricsNoSuchObject(t *testing.T) {
+	values, oids := interfaceValues()
+	// The agent answers NoSuchObject for OIDs it has no value for
+	delete(values, oids[OIDIfHCInOctets])
+	agent := snmptest.NewAgent(t, values)
+	client := snmp.Client{Target: agent.Target, Community: "public", Timeout: time.Second, Retries: -1}
+
+	metrics, err := GetInterfaceMetrics(&client, 3)
+	if err != nil {
+		t.Fatalf("GetInterfaceMetrics returned error: %v", err)
+	}
+	if metrics.Name != "Gi0/3" || metrics.In != 1000 || metrics.Out != 2000 || metrics.HCOut != 2000 || metrics.Speed != 1000000000 {
+		t.Errorf("GetInterfaceMetrics = %+v, want the values the agent returned", *metrics)
+	}
+	if !metrics.LowCapacity || metrics.HCIn != 0 {
+		t.Errorf("LowCapacity = %t, HCIn = %d, want the 32-bit fallback without ifHCInOctets", metrics.LowCapacity, metrics.HCIn)
+	}
+}
+
+func TestGetInterfaceMetricsMissingVarbind(t *testing.T) {
+	tests := []struct {
+		name            string
+		omit            []string
+		wantName        string
+		wantLowCapacity bool
+		wantErr         bool
+	}{
+		{"ifHCInOctets", []string{OIDIfHCInOctets}, "Gi0/3", true, false},
+		{"ifName", []string{OIDIfName}, "GigabitEthernet0/3", false, false},
+		{"first and last", []string{OIDIfDescr, OIDIfSpeed}, "Gi0/3", false, false},
+		{"ifName and ifDescr", []string{OIDIfName, OIDIfDescr}, "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, oids := interfaceValues()
+			agent := snmptest.NewAgent(t, values)
+			for _, column := range tt.omit {
+				agent.Omit(oids[column])
+			}
+			client := snmp.Client{Target: agent.Target, Community: "public", Timeout: time.Second, Retries: -1}
+
+			metrics, err := GetInterfaceMetrics(&client, 3)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("GetInterfaceMetrics = %+v, want an error", *metrics)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetInterfaceMetrics returned error: %v", err)
+			}
+			if metrics.Name != tt.wantName || metrics.LowCapacity != tt.wantLowCapacity {
+				t.Errorf("Name = %q, LowCapacity = %t, want %q, %t", metrics.Name, metrics.LowCapacity, tt.wantName, tt.wantLowCapacity)
+			}
+			// The counters are matched by name, so they never shift to another column
+			if metrics.In != 1000 || metrics.Out != 2000 || metrics.HCOut != 2000 || metrics.HighSpeed != 1000 {
+				t.Errorf("GetInterfaceMetrics = %+v, want the counters of their own columns", *metrics)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package logging sets up the leveled logger of the commands. Diagnostics are written to stderr, since
+// monitoring schedulers read the status line of a check from stdout.
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// DefaultLevel is the level logged by default. Warnings and errors are shown, while diagnostics such as
+// unexpected value types from an agent are only shown at debug level.
+const DefaultLevel = "warn"
+
+// ParseLevel returns the slog level named by level: debug, info, warn or error.
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("invalid log level '%s', expected debug, info, warn or error", level)
+	}
+}
+
+// Setup makes the default slog logger write records of at least the named level to stderr.
+//
+// Example usage:
+//
+//	if err := logging.Setup("debug"); err != nil {
+//	    log.Fatal(err)
+//	}
+//	slog.Debug("Unexpected value type", "oid", oid)
+func Setup(level string) error {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: lvl})))
+	return nil
+}
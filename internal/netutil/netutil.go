@@ -0,0 +1,145 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package netutil
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// maxTargets is the maximum number of targets a single spec is allowed to expand to.
+// It protects callers from accidentally expanding something like an IPv6 /64.
+const (
+	maxTargets = 65536
+)
+
+// ExpandTargets turns a target specification into a list of individual targets.
+// The network and broadcast addresses of IPv4 CIDRs are excluded.
+// See ExpandTargetsInclusive for the supported forms of spec.
+//
+// Example usage:
+//
+//	targets, err := netutil.ExpandTargets("192.0.2.0/29")
+//	// targets: 192.0.2.1 ... 192.0.2.6
+func ExpandTargets(spec string) ([]string, error) {
+	return expandTargets(spec, true)
+}
+
+// ExpandTargetsInclusive turns a target specification into a list of individual targets,
+// keeping the network and broadcast addresses of CIDRs.
+// The following forms of spec are supported:
+//   - A single IP address or hostname, e.g. "192.0.2.1" or "core-sw1", returned as is.
+//   - A CIDR, e.g. "192.0.2.0/29" or "2001:db8::/126".
+//   - An inclusive range, e.g. "192.0.2.1-192.0.2.20" or the shorthand "192.0.2.1-20".
+func ExpandTargetsInclusive(spec string) ([]string, error) {
+	return expandTargets(spec, false)
+}
+
+// expandTargets implements ExpandTargets and ExpandTargetsInclusive.
+func expandTargets(spec string, excludeNetworkBroadcast bool) ([]string, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("empty target specification")
+	}
+
+	if strings.Contains(spec, "/") {
+		prefix, err := netip.ParsePrefix(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %s: %w", spec, err)
+		}
+		prefix = prefix.Masked()
+		first := prefix.Addr()
+		last := lastAddr(prefix)
+		targets, err := expandRange(first, last)
+		if err != nil {
+			return nil, err
+		}
+		if excludeNetworkBroadcast && first.Is4() && prefix.Bits() <= 30 {
+			targets = targets[1 : len(targets)-1]
+		}
+		return targets, nil
+	}
+
+	if start, end, ok := strings.Cut(spec, "-"); ok {
+		first, err := netip.ParseAddr(start)
+		if err == nil {
+			last, err := parseRangeEnd(first, end)
+			if err != nil {
+				return nil, fmt.Errorf("invalid range %s: %w", spec, err)
+			}
+			return expandRange(first, last)
+		}
+		// Not an address range, so treat it as a hostname containing a dash.
+	}
+
+	if addr, err := netip.ParseAddr(spec); err == nil {
+		return []string{addr.String()}, nil
+	}
+
+	return []string{spec}, nil
+}
+
+// parseRangeEnd parses the end of an a-b range, which is either a full address of the same family
+// as first or, for IPv4, just the last octet.
+func parseRangeEnd(first netip.Addr, end string) (netip.Addr, error) {
+	if last, err := netip.ParseAddr(end); err == nil {
+		if last.Is4() != first.Is4() {
+			return netip.Addr{}, fmt.Errorf("mixed address families")
+		}
+		return last, nil
+	}
+
+	octet, err := strconv.ParseUint(end, 10, 8)
+	if err != nil || !first.Is4() {
+		return netip.Addr{}, fmt.Errorf("invalid range end %s", end)
+	}
+	bytes := first.As4()
+	bytes[3] = byte(octet)
+	return netip.AddrFrom4(bytes), nil
+}
+
+// lastAddr returns the highest address contained in the masked prefix.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	bytes := prefix.Addr().AsSlice()
+	for bit := prefix.Bits(); bit < len(bytes)*8; bit++ {
+		bytes[bit/8] |= 0x80 >> (bit % 8)
+	}
+	addr, _ := netip.AddrFromSlice(bytes)
+	return addr
+}
+
+// expandRange returns every address from first to last inclusive.
+func expandRange(first netip.Addr, last netip.Addr) ([]string, error) {
+	if last.Less(first) {
+		return nil, fmt.Errorf("range end %s is before start %s", last, first)
+	}
+
+	var targets []string
+	for addr := first; ; addr = addr.Next() {
+		if len(targets) == maxTargets {
+			return nil, fmt.Errorf("range %s-%s expands to more than %d targets", first, last, maxTargets)
+		}
+		targets = append(targets, addr.String())
+		if addr == last {
+			break
+		}
+	}
+
+	return targets, nil
+}
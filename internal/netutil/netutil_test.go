@@ -0,0 +1,81 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package netutil
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestExpandTargets(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"192.0.2.0/29", "[192.0.2.1 192.0.2.2 192.0.2.3 192.0.2.4 192.0.2.5 192.0.2.6]"},
+		{"192.0.2.5/29", "[192.0.2.1 192.0.2.2 192.0.2.3 192.0.2.4 192.0.2.5 192.0.2.6]"},
+		{"192.0.2.0/31", "[192.0.2.0 192.0.2.1]"},
+		{"192.0.2.7/32", "[192.0.2.7]"},
+		{"2001:db8::/126", "[2001:db8:: 2001:db8::1 2001:db8::2 2001:db8::3]"},
+		{"192.0.2.1-192.0.2.4", "[192.0.2.1 192.0.2.2 192.0.2.3 192.0.2.4]"},
+		{"192.0.2.254-192.0.3.1", "[192.0.2.254 192.0.2.255 192.0.3.0 192.0.3.1]"},
+		{"192.0.2.1-3", "[192.0.2.1 192.0.2.2 192.0.2.3]"},
+		{"192.0.2.1", "[192.0.2.1]"},
+		{" 192.0.2.1 ", "[192.0.2.1]"},
+		{"2001:db8::1", "[2001:db8::1]"},
+		{"core-sw1", "[core-sw1]"},
+		{"core-sw1.example.com", "[core-sw1.example.com]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			targets, err := ExpandTargets(tt.spec)
+			if err != nil {
+				t.Fatalf("ExpandTargets(%q) returned error: %v", tt.spec, err)
+			}
+			if got := fmt.Sprint(targets); got != tt.want {
+				t.Errorf("ExpandTargets(%q) = %s, want %s", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandTargetsInclusive(t *testing.T) {
+	targets, err := ExpandTargetsInclusive("192.0.2.0/30")
+	if err != nil {
+		t.Fatalf("ExpandTargetsInclusive returned error: %v", err)
+	}
+	if got, want := fmt.Sprint(targets), "[192.0.2.0 192.0.2.1 192.0.2.2 192.0.2.3]"; got != want {
+		t.Errorf("ExpandTargetsInclusive = %s, want %s", got, want)
+	}
+}
+
+func TestExpandTargetsInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"192.0.2.0/33",
+		"192.0.2.300/24",
+		"192.0.2.4-192.0.2.1",
+		"192.0.2.1-300",
+		"192.0.2.1-2001:db8::1",
+		"2001:db8::1-5",
+		"10.0.0.0/8",
+	} {
+		if targets, err := ExpandTargets(spec); err == nil {
+			t.Errorf("ExpandTargets(%q) = %v, want an error", spec, targets)
+		}
+	}
+}
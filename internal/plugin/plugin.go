@@ -0,0 +1,116 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package plugin holds helpers shared by the check commands for reporting results the way
+// Nagios-compatible schedulers expect: the status on stdout and the matching exit code.
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dmabry/gochecks/internal/snmp"
+	"github.com/dmabry/gomonitor"
+)
+
+// Exit sends a result with the given status and message and exits with the status's exit code:
+// 0 for OK, 1 for Warning, 2 for Critical and 3 for Unknown. It never returns.
+// Checks use Unknown for invalid flags or configuration, and ErrorStatus when the target cannot be queried.
+//
+// Example:
+//
+//	pattern, err := regexp.Compile(*filter)
+//	if err != nil {
+//	    plugin.Exit(gomonitor.Unknown, fmt.Sprintf("Invalid filter '%s': %s", *filter, err))
+//	}
+func Exit(status gomonitor.ExitCode, message string) {
+	checkResult := gomonitor.NewCheckResult()
+	checkResult.SetResult(status, message)
+	checkResult.SendResult()
+}
+
+// WorseStatus returns the more severe of two check statuses, ranking Critical above Warning above Unknown above OK,
+// so a check combining several evaluations can report the worst of them.
+//
+// Example:
+//
+//	status := plugin.WorseStatus(operStatus, errorStatus)
+func WorseStatus(a gomonitor.ExitCode, b gomonitor.ExitCode) gomonitor.ExitCode {
+	severity := map[gomonitor.ExitCode]int{gomonitor.OK: 0, gomonitor.Unknown: 1, gomonitor.Warning: 2, gomonitor.Critical: 3}
+	if severity[b] > severity[a] {
+		return b
+	}
+	return a
+}
+
+// PrefixSysName prepends the sysName of the SNMP target to the message of checkResult, e.g.
+// "core-sw1: 2 interface(s) down", so alerts name the device rather than only an interface or a count.
+// When the sysName cannot be read, the target is prepended instead.
+//
+// Example:
+//
+//	result := CheckInterfaceMetrics(&snmpClient, options)
+//	if *resolveName {
+//	    plugin.PrefixSysName(&snmpClient, result)
+//	}
+//	result.SendResult()
+func PrefixSysName(snmpClient *snmp.Client, checkResult *gomonitor.CheckResult) {
+	name, err := snmpClient.SysName()
+	if err != nil {
+		name = snmpClient.Target
+	}
+	checkResult.Message = name + ": " + checkResult.Message
+}
+
+// ErrorStatus returns the status a check reports for an error returned by the SNMP client: Unknown when the
+// target timed out, since the state of what is checked could not be determined, and Critical otherwise.
+//
+// Example:
+//
+//	result, _, err := snmpClient.GetValue(oids)
+//	if err != nil {
+//	    plugin.Exit(plugin.ErrorStatus(err), fmt.Sprintf("SNMP target %s failed to return data. %s", snmpClient.Target, err))
+//	}
+func ErrorStatus(err error) gomonitor.ExitCode {
+	if errors.Is(err, snmp.ErrTimeout) {
+		return gomonitor.Unknown
+	}
+	return gomonitor.Critical
+}
+
+// Validate pings the SNMP target and exits with an Unknown result describing whether it is reachable and
+// accepted the credentials, along with the latency. It never returns. Commands call it for -validate, as a
+// pre-flight before rolling out new credentials, instead of running their check.
+//
+// Example:
+//
+//	if snmpFlags.Validate {
+//	    plugin.Validate(&snmpClient)
+//	}
+func Validate(snmpClient *snmp.Client) {
+	latency, err := snmpClient.Ping()
+	switch {
+	case err == nil:
+		Exit(gomonitor.Unknown, fmt.Sprintf("Validate: SNMP target %s is reachable and accepted the credentials (latency %s)", snmpClient.Target, latency))
+	case errors.Is(err, snmp.ErrTimeout):
+		Exit(gomonitor.Unknown, fmt.Sprintf("Validate: SNMP target %s timed out, it is unreachable or rejected the credentials. %s", snmpClient.Target, err))
+	case errors.Is(err, snmp.ErrAuth):
+		Exit(gomonitor.Unknown, fmt.Sprintf("Validate: SNMP target %s is reachable but rejected the credentials. %s", snmpClient.Target, err))
+	case errors.Is(err, snmp.ErrConnect):
+		Exit(gomonitor.Unknown, fmt.Sprintf("Validate: SNMP target %s refused the connection. %s", snmpClient.Target, err))
+	default:
+		Exit(gomonitor.Unknown, fmt.Sprintf("Validate: SNMP target %s failed. %s", snmpClient.Target, err))
+	}
+}
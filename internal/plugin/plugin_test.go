@@ -0,0 +1,113 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dmabry/gochecks/internal/snmp"
+	"github.com/dmabry/gomonitor"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// exitStatusEnv holds the status TestExit re-executes the test binary with, making TestExitHelper call Exit.
+const exitStatusEnv = "GOCHECKS_TEST_EXIT_STATUS"
+
+// TestExitHelper calls Exit with the status in exitStatusEnv. It does nothing unless run by TestExit.
+func TestExitHelper(t *testing.T) {
+	status, ok := os.LookupEnv(exitStatusEnv)
+	if !ok {
+		t.Skip("only run by TestExit")
+	}
+	code, err := strconv.Atoi(status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Exit(gomonitor.ExitCode(code), "exit test")
+}
+
+func TestExit(t *testing.T) {
+	tests := []struct {
+		status     gomonitor.ExitCode
+		wantCode   int
+		wantOutput string
+	}{
+		{gomonitor.OK, 0, "OK - exit test"},
+		{gomonitor.Warning, 1, "Warning - exit test"},
+		{gomonitor.Critical, 2, "Critical - exit test"},
+		{gomonitor.Unknown, 3, "Unknown - exit test"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status.String(), func(t *testing.T) {
+			cmd := exec.Command(os.Args[0], "-test.run=^TestExitHelper$")
+			cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%d", exitStatusEnv, tt.status.Int()))
+			output, err := cmd.Output()
+
+			code := 0
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				code = exitErr.ExitCode()
+			} else if err != nil {
+				t.Fatalf("failed to run the test binary: %v", err)
+			}
+			if code != tt.wantCode {
+				t.Errorf("Exit(%s) exited with %d, want %d", tt.status, code, tt.wantCode)
+			}
+			if !strings.HasPrefix(string(output), tt.wantOutput+"\n") {
+				t.Errorf("Exit(%s) printed %q, want %q", tt.status, output, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want gomonitor.ExitCode
+	}{
+		{"timeout", &snmp.Error{Kind: snmp.ErrTimeout, Err: errors.New("request timeout (after 1 retries)")}, gomonitor.Unknown},
+		{"wrapped timeout", fmt.Errorf("interface index 3: %w", &snmp.Error{Kind: snmp.ErrTimeout, Err: errors.New("timeout")}), gomonitor.Unknown},
+		{"connection refused", &snmp.Error{Kind: snmp.ErrConnect, Err: errors.New("connection refused")}, gomonitor.Critical},
+		{"authentication", &snmp.Error{Kind: snmp.ErrAuth, Err: errors.New("wrong digest")}, gomonitor.Critical},
+		{"other", errors.New("unexpected response"), gomonitor.Critical},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := ErrorStatus(tt.err); status != tt.want {
+				t.Errorf("ErrorStatus(%v) = %s, want %s", tt.err, status, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorseStatus(t *testing.T) {
+	statuses := []gomonitor.ExitCode{gomonitor.OK, gomonitor.Unknown, gomonitor.Warning, gomonitor.Critical}
+	// statuses is ordered by severity, so the worse of two is the one with the higher position
+	for i, a := range statuses {
+		for j, b := range statuses {
+			want := statuses[max(i, j)]
+			if got := WorseStatus(a, b); got != want {
+				t.Errorf("WorseStatus(%s, %s) = %s, want %s", a, b, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package snmp
+
+import (
+	"context"
+	"errors"
+	"github.com/dmabry/gochecks/internal/snmp/snmptest"
+	"testing"
+	"time"
+)
+
+// unresponsiveClient returns a client of an agent that never answers, with a timeout far longer than the tests
+// wait, so only the context can end its requests early.
+func unresponsiveClient(t *testing.T) *Client {
+	t.Helper()
+	agent := snmptest.NewAgent(t, nil)
+	agent.Drop(1 << 30)
+	return &Client{Target: agent.Target, Community: "public", Timeout: 10 * time.Second, Retries: 2, Attempts: 3}
+}
+
+func TestGetValueContextCanceled(t *testing.T) {
+	client := unresponsiveClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, _, err := client.GetValueContext(ctx, []string{".1.3.6.1.2.1.1.5.0"})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("GetValueContext returned %v, want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("GetValueContext returned after %s, want promptly after the cancellation", elapsed)
+	}
+}
+
+func TestGetValueContextDeadline(t *testing.T) {
+	client := unresponsiveClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, _, err := client.GetValueContext(ctx, []string{".1.3.6.1.2.1.1.5.0"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetValueContext returned %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("GetValueContext returned after %s, want promptly after the deadline", elapsed)
+	}
+}
+
+func TestWalkContextCanceled(t *testing.T) {
+	client := unresponsiveClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, _, err := client.WalkContext(ctx, ".1.3.6.1.2.1.2.2.1.2")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("WalkContext returned %v, want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("WalkContext returned after %s, want promptly after the cancellation", elapsed)
+	}
+}
+
+func TestGetValueContextAlreadyCanceled(t *testing.T) {
+	client := unresponsiveClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, _, err := client.GetValueContext(ctx, []string{".1.3.6.1.2.1.1.5.0"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetValueContext returned %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,67 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package snmp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ToFloat64 coerces an SNMP value, as returned by gosnmp, into a float64.
+// Integers, counters, gauges and timeticks are converted directly, while octet strings are
+// parsed as decimal numbers, since many agents encode numeric values as strings.
+// An error is returned for nil values and values that are not numeric.
+func ToFloat64(value interface{}) (float64, error) {
+	switch val := value.(type) {
+	case int:
+		return float64(val), nil
+	case int32:
+		return float64(val), nil
+	case int64:
+		return float64(val), nil
+	case uint:
+		return float64(val), nil
+	case uint32:
+		return float64(val), nil
+	case uint64:
+		return float64(val), nil
+	case float32:
+		return float64(val), nil
+	case float64:
+		return val, nil
+	case []byte:
+		number, err := strconv.ParseFloat(strings.TrimSpace(string(val)), 64)
+		if err != nil {
+			return 0, fmt.Errorf("value %q is not numeric", val)
+		}
+		return number, nil
+	case nil:
+		return 0, fmt.Errorf("value is empty")
+	default:
+		return 0, fmt.Errorf("value of type %T is not numeric: %v", value, value)
+	}
+}
+
+// ToString coerces an SNMP value, as returned by gosnmp, into a string.
+// Octet strings are converted as text and every other type uses its default formatting.
+func ToString(value interface{}) string {
+	if val, ok := value.([]byte); ok {
+		return string(val)
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,78 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package snmp
+
+import (
+	"testing"
+)
+
+func TestToFloat64(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  float64
+	}{
+		{"Integer", -7, -7},
+		{"int32", int32(-7), -7},
+		{"int64", int64(1 << 40), 1 << 40},
+		{"Gauge32", uint(42), 42},
+		{"Counter32", uint32(4294967295), 4294967295},
+		{"Counter64", uint64(1 << 62), 1 << 62},
+		{"float32", float32(0.5), 0.5},
+		{"float64", 2.25, 2.25},
+		{"numeric OctetString", []byte("12.5"), 12.5},
+		{"padded OctetString", []byte(" 17\n"), 17},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToFloat64(tt.value)
+			if err != nil {
+				t.Fatalf("ToFloat64(%v) returned error: %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("ToFloat64(%v) = %g, want %g", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToFloat64Invalid(t *testing.T) {
+	for _, value := range []interface{}{nil, []byte("up"), []byte(""), "42", true} {
+		if got, err := ToFloat64(value); err == nil {
+			t.Errorf("ToFloat64(%#v) = %g, want an error", value, got)
+		}
+	}
+}
+
+func TestToString(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{[]byte("core-sw1"), "core-sw1"},
+		{[]byte{}, ""},
+		{42, "42"},
+		{uint32(4294967295), "4294967295"},
+		{"text", "text"},
+		{nil, "<nil>"},
+	}
+	for _, tt := range tests {
+		if got := ToString(tt.value); got != tt.want {
+			t.Errorf("ToString(%#v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
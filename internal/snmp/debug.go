@@ -0,0 +1,47 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package snmp
+
+import (
+	"fmt"
+	"github.com/gosnmp/gosnmp"
+	"log/slog"
+	"strings"
+)
+
+// slogWriter adapts gosnmp's debug logging to the default slog logger, so the on-wire traces of a
+// Debug client are written to stderr at debug level alongside the other diagnostics.
+type slogWriter struct{}
+
+// Print logs the trace v at debug level.
+func (slogWriter) Print(v ...interface{}) {
+	slog.Debug(strings.TrimSpace(fmt.Sprint(v...)), "source", "gosnmp")
+}
+
+// Printf logs the trace formatted from format and v at debug level.
+func (slogWriter) Printf(format string, v ...interface{}) {
+	slog.Debug(strings.TrimSpace(fmt.Sprintf(format, v...)), "source", "gosnmp")
+}
+
+// logger returns the gosnmp logger of the client: the slog adapter when Debug is set, and a disabled
+// logger otherwise.
+func (s *Client) logger() gosnmp.Logger {
+	if !s.Debug {
+		return gosnmp.Logger{}
+	}
+	return gosnmp.NewLogger(slogWriter{})
+}
@@ -0,0 +1,101 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package snmp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gosnmp/gosnmp"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// The kinds of SNMP errors returned by the client and its sessions, which callers can test with errors.Is
+// to tell a target that did not answer from one that answered without the requested data.
+var (
+	// ErrTimeout means the target did not respond within the timeout and retries.
+	// SNMPv1/v2c agents silently drop requests with a wrong community, so it may also mean the community was rejected.
+	ErrTimeout = errors.New("snmp: request timed out")
+	// ErrConnect means the connection to the target could not be established or was refused.
+	ErrConnect = errors.New("snmp: connection failed")
+	// ErrAuth means an SNMPv3 target rejected the credentials.
+	ErrAuth = errors.New("snmp: authentication failed")
+	// ErrNoSuchObject means the target does not implement the requested object or instance.
+	ErrNoSuchObject = errors.New("snmp: no such object")
+)
+
+// Error is an SNMP error of a known kind. errors.Is matches both its Kind and the underlying error.
+type Error struct {
+	Kind error
+	Err  error
+}
+
+// Error returns the message of the underlying error.
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the kind and the underlying error.
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// classify wraps err in an *Error of the matching kind. Errors of an unknown kind, cancellations of the
+// context and errors already classified are returned unchanged.
+func classify(err error) error {
+	var snmpErr *Error
+	switch {
+	case err == nil, errors.As(err, &snmpErr):
+		return err
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return err
+	case errors.Is(err, gosnmp.ErrWrongDigest), errors.Is(err, gosnmp.ErrUnknownUsername),
+		errors.Is(err, gosnmp.ErrDecryption), errors.Is(err, gosnmp.ErrUnknownSecurityLevel):
+		return &Error{Kind: ErrAuth, Err: err}
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET):
+		return &Error{Kind: ErrConnect, Err: err}
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return &Error{Kind: ErrTimeout, Err: err}
+	}
+	// gosnmp reports exhausted retries as a plain "request timeout" error
+	if strings.Contains(err.Error(), "timeout") {
+		return &Error{Kind: ErrTimeout, Err: err}
+	}
+	return err
+}
+
+// VariableError returns an *Error of kind ErrNoSuchObject when the agent answered variable with
+// NoSuchObject, NoSuchInstance or EndOfMibView instead of a value, and nil otherwise.
+//
+// Example usage:
+//
+//	result, _, err := client.GetValue([]string{oid})
+//	...
+//	if err := snmp.VariableError(result.Variables[0]); err != nil {
+//	    // the target does not implement oid
+//	}
+func VariableError(variable gosnmp.SnmpPDU) error {
+	switch variable.Type {
+	case gosnmp.NoSuchObject, gosnmp.NoSuchInstance, gosnmp.EndOfMibView:
+		return &Error{Kind: ErrNoSuchObject, Err: fmt.Errorf("%s: %s", variable.Name, variable.Type)}
+	}
+	return nil
+}
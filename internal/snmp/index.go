@@ -0,0 +1,62 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package snmp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TableIndex returns the index of oid, a walked instance of the column columnOid, as the complete OID
+// suffix after the column. Tables such as ipAddressTable or ifStackTable have indexes made of several
+// elements, so the index is returned as a string, e.g. "1.4.192.0.2.1", rather than the last element only.
+// Leading dots are ignored. It returns an error when oid is not an instance of the column.
+//
+// Example usage:
+//
+//	index, err := snmp.TableIndex(".1.3.6.1.2.1.31.1.1.1.18", ".1.3.6.1.2.1.31.1.1.1.18.3")
+//	// index: "3"
+func TableIndex(columnOid string, oid string) (string, error) {
+	prefix := strings.Trim(columnOid, ".") + "."
+	index, found := strings.CutPrefix(strings.TrimPrefix(oid, "."), prefix)
+	if !found || index == "" {
+		return "", fmt.Errorf("OID %s is not an instance of %s", oid, columnOid)
+	}
+	return index, nil
+}
+
+// ColumnIndex splits oid, a walked instance of the table entry entryOid, into its column number and its
+// index, which may have several elements as described for TableIndex.
+// It returns an error when oid is not an instance of a column of the entry.
+//
+// Example usage:
+//
+//	column, index, err := snmp.ColumnIndex(".1.3.6.1.2.1.2.2.1", ".1.3.6.1.2.1.2.2.1.10.3")
+//	// column: 10, index: "3"
+func ColumnIndex(entryOid string, oid string) (int, string, error) {
+	suffix, err := TableIndex(entryOid, oid)
+	if err != nil {
+		return 0, "", err
+	}
+	column, index, found := strings.Cut(suffix, ".")
+	columnNumber, err := strconv.Atoi(column)
+	if !found || err != nil {
+		return 0, "", fmt.Errorf("OID %s is not a column of table %s", oid, entryOid)
+	}
+	return columnNumber, index, nil
+}
@@ -0,0 +1,97 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package snmp
+
+import (
+	"testing"
+)
+
+func TestTableIndex(t *testing.T) {
+	tests := []struct {
+		name   string
+		column string
+		oid    string
+		want   string
+	}{
+		{"single element", ".1.3.6.1.2.1.31.1.1.1.18", ".1.3.6.1.2.1.31.1.1.1.18.3", "3"},
+		{"ipAddressTable", ".1.3.6.1.2.1.4.34.1.3", ".1.3.6.1.2.1.4.34.1.3.1.4.192.0.2.1", "1.4.192.0.2.1"},
+		{"ifStackTable", ".1.3.6.1.2.1.31.1.2.1.3", ".1.3.6.1.2.1.31.1.2.1.3.0.5", "0.5"},
+		{"no leading dots", "1.3.6.1.2.1.25.4.2.1.2", "1.3.6.1.2.1.25.4.2.1.2.1042", "1042"},
+		{"mixed leading dots", "1.3.6.1.2.1.25.4.2.1.2", ".1.3.6.1.2.1.25.4.2.1.2.1042", "1042"},
+		{"trailing dot on column", ".1.3.6.1.2.1.25.4.2.1.2.", ".1.3.6.1.2.1.25.4.2.1.2.1042", "1042"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index, err := TableIndex(tt.column, tt.oid)
+			if err != nil {
+				t.Fatalf("TableIndex(%s, %s) returned error: %v", tt.column, tt.oid, err)
+			}
+			if index != tt.want {
+				t.Errorf("TableIndex(%s, %s) = %q, want %q", tt.column, tt.oid, index, tt.want)
+			}
+		})
+	}
+}
+
+func TestTableIndexInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		column string
+		oid    string
+	}{
+		{"other column", ".1.3.6.1.2.1.2.2.1.2", ".1.3.6.1.2.1.2.2.1.3.1"},
+		{"column sharing a prefix", ".1.3.6.1.2.1.2.2.1.1", ".1.3.6.1.2.1.2.2.1.10.1"},
+		{"column itself", ".1.3.6.1.2.1.2.2.1.2", ".1.3.6.1.2.1.2.2.1.2"},
+		{"empty index", ".1.3.6.1.2.1.2.2.1.2", ".1.3.6.1.2.1.2.2.1.2."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if index, err := TableIndex(tt.column, tt.oid); err == nil {
+				t.Errorf("TableIndex(%s, %s) = %q, want an error", tt.column, tt.oid, index)
+			}
+		})
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	tests := []struct {
+		entry      string
+		oid        string
+		wantColumn int
+		wantIndex  string
+	}{
+		{".1.3.6.1.2.1.2.2.1", ".1.3.6.1.2.1.2.2.1.10.3", 10, "3"},
+		{".1.3.6.1.2.1.4.34.1", ".1.3.6.1.2.1.4.34.1.3.2.16.32.1.13.184.0.0.0.0.0.0.0.0.0.0.0.1", 3,
+			"2.16.32.1.13.184.0.0.0.0.0.0.0.0.0.0.0.1"},
+	}
+	for _, tt := range tests {
+		column, index, err := ColumnIndex(tt.entry, tt.oid)
+		if err != nil {
+			t.Errorf("ColumnIndex(%s, %s) returned error: %v", tt.entry, tt.oid, err)
+			continue
+		}
+		if column != tt.wantColumn || index != tt.wantIndex {
+			t.Errorf("ColumnIndex(%s, %s) = %d, %q, want %d, %q", tt.entry, tt.oid, column, index, tt.wantColumn, tt.wantIndex)
+		}
+	}
+
+	for _, oid := range []string{".1.3.6.1.2.1.2.2.1.10", ".1.3.6.1.2.1.2.2.1.x.3", ".1.3.6.1.2.1.2.2.2.10.3"} {
+		if column, index, err := ColumnIndex(".1.3.6.1.2.1.2.2.1", oid); err == nil {
+			t.Errorf("ColumnIndex(.1.3.6.1.2.1.2.2.1, %s) = %d, %q, want an error", oid, column, index)
+		}
+	}
+}
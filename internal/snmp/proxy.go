@@ -0,0 +1,201 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package snmp
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"github.com/gosnmp/gosnmp"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// connectViaProxy connects to the SNMP target through the proxy configured on the client.
+// The proxy is given as a URL, either socks5://[user:pass@]host:port or http://host:port.
+// gosnmp dials the proxy itself over TCP and the tunnel to the target is then negotiated on that
+// connection, so every SNMP request flows through the proxy.
+//
+// Both SOCKS5 CONNECT and HTTP CONNECT only carry TCP streams, so SNMP over a proxy always uses
+// the TCP transport and the target must accept SNMP over TCP (RFC 3430). Relaying SNMP over UDP
+// requires a UDP relay on the jump host that is addressed directly as the target instead.
+func (s *Client) connectViaProxy(ctx context.Context) (*gosnmp.GoSNMP, error) {
+	proxyURL, err := url.Parse(s.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy %s: %w", s.Proxy, err)
+	}
+	proxyPort, err := strconv.ParseUint(proxyURL.Port(), 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy port in %s", s.Proxy)
+	}
+
+	snmpClient := &gosnmp.GoSNMP{
+		Target:         proxyURL.Hostname(),
+		Port:           uint16(proxyPort),
+		Transport:      "tcp",
+		Community:      s.Community,
+		Version:        gosnmp.Version2c,
+		Context:        ctx,
+		Timeout:        s.timeout(),
+		Retries:        s.retries(),
+		MaxRepetitions: s.maxRepetitions(),
+		NonRepeaters:   s.NonRepeaters,
+		MaxOids:        s.maxOidsPerPDU(),
+		Logger:         s.logger(),
+	}
+
+	if err := snmpClient.Connect(); err != nil {
+		return nil, &Error{Kind: ErrConnect, Err: err}
+	}
+
+	targetHost, targetPort, err := splitTarget(s.Target)
+	if err != nil {
+		snmpClient.Conn.Close()
+		return nil, err
+	}
+	targetAddr := net.JoinHostPort(targetHost, strconv.Itoa(int(targetPort)))
+	if err := snmpClient.Conn.SetDeadline(time.Now().Add(snmpClient.Timeout)); err != nil {
+		snmpClient.Conn.Close()
+		return nil, err
+	}
+	switch proxyURL.Scheme {
+	case "socks5":
+		err = socks5Connect(snmpClient.Conn, proxyURL.User, targetAddr)
+	case "http":
+		err = httpConnect(snmpClient.Conn, targetAddr)
+	default:
+		err = fmt.Errorf("unsupported proxy scheme '%s'", proxyURL.Scheme)
+	}
+	if err == nil {
+		err = snmpClient.Conn.SetDeadline(time.Time{})
+	}
+	if err != nil {
+		snmpClient.Conn.Close()
+		return nil, &Error{Kind: ErrConnect, Err: fmt.Errorf("proxy %s failed to connect to %s: %w", proxyURL.Host, targetAddr, err)}
+	}
+
+	return snmpClient, nil
+}
+
+// socks5Connect negotiates a SOCKS5 (RFC 1928) CONNECT to targetAddr over conn, authenticating
+// with username/password (RFC 1929) when user is set.
+func socks5Connect(conn net.Conn, user *url.Userinfo, targetAddr string) error {
+	method := byte(0x00) // no authentication
+	if user != nil {
+		method = 0x02 // username/password
+	}
+	if _, err := conn.Write([]byte{0x05, 0x01, method}); err != nil {
+		return err
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != 0x05 || reply[1] != method {
+		return fmt.Errorf("socks5 authentication method rejected")
+	}
+
+	if user != nil {
+		password, _ := user.Password()
+		auth := []byte{0x01, byte(len(user.Username()))}
+		auth = append(auth, user.Username()...)
+		auth = append(auth, byte(len(password)))
+		auth = append(auth, password...)
+		if _, err := conn.Write(auth); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return err
+		}
+		if reply[1] != 0x00 {
+			return fmt.Errorf("socks5 authentication failed")
+		}
+	}
+
+	host, port, err := net.SplitHostPort(targetAddr)
+	if err != nil {
+		return err
+	}
+	portNumber, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return err
+	}
+	request := []byte{0x05, 0x01, 0x00}
+	if addr, err := netip.ParseAddr(host); err == nil && addr.Is4() {
+		request = append(request, 0x01)
+		request = append(request, addr.AsSlice()...)
+	} else if err == nil {
+		request = append(request, 0x04)
+		request = append(request, addr.AsSlice()...)
+	} else {
+		request = append(request, 0x03, byte(len(host)))
+		request = append(request, host...)
+	}
+	request = binary.BigEndian.AppendUint16(request, uint16(portNumber))
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	if header[1] != 0x00 {
+		return fmt.Errorf("socks5 connect failed with code %d", header[1])
+	}
+	var boundLen int
+	switch header[3] {
+	case 0x01:
+		boundLen = net.IPv4len
+	case 0x04:
+		boundLen = net.IPv6len
+	case 0x03:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return err
+		}
+		boundLen = int(length[0])
+	default:
+		return fmt.Errorf("socks5 reply has unknown address type %d", header[3])
+	}
+	// Discard the bound address and port
+	_, err = io.ReadFull(conn, make([]byte, boundLen+2))
+	return err
+}
+
+// httpConnect negotiates an HTTP CONNECT tunnel to targetAddr over conn.
+func httpConnect(conn net.Conn, targetAddr string) error {
+	request := fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n\r\n", targetAddr, targetAddr)
+	if _, err := conn.Write([]byte(request)); err != nil {
+		return err
+	}
+	response, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("http connect failed: %s", response.Status)
+	}
+	return nil
+}
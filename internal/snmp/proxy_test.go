@@ -0,0 +1,257 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package snmp
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+// startProxy starts a TCP proxy on a local port that negotiates each connection with handshake and sends the
+// target it was asked to connect to on the returned channel. Each connection is closed after its handshake.
+func startProxy(t *testing.T, handshake func(conn net.Conn) (string, error)) (string, <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	targets := make(chan string, 1)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			target, err := handshake(conn)
+			conn.Close()
+			if err != nil {
+				target = "error: " + err.Error()
+			}
+			targets <- target
+		}
+	}()
+	return listener.Addr().String(), targets
+}
+
+// socks5Server returns a SOCKS5 handshake that requires user and password when user is set, and replies to the
+// CONNECT request with code.
+func socks5Server(user string, password string, code byte) func(conn net.Conn) (string, error) {
+	return func(conn net.Conn) (string, error) {
+		greeting := make([]byte, 2)
+		if _, err := io.ReadFull(conn, greeting); err != nil {
+			return "", err
+		}
+		methods := make([]byte, greeting[1])
+		if _, err := io.ReadFull(conn, methods); err != nil {
+			return "", err
+		}
+		method := byte(0x00)
+		if user != "" {
+			method = 0x02
+		}
+		if _, err := conn.Write([]byte{0x05, method}); err != nil {
+			return "", err
+		}
+
+		if user != "" {
+			header := make([]byte, 2)
+			if _, err := io.ReadFull(conn, header); err != nil {
+				return "", err
+			}
+			username := make([]byte, header[1])
+			if _, err := io.ReadFull(conn, username); err != nil {
+				return "", err
+			}
+			length := make([]byte, 1)
+			if _, err := io.ReadFull(conn, length); err != nil {
+				return "", err
+			}
+			pass := make([]byte, length[0])
+			if _, err := io.ReadFull(conn, pass); err != nil {
+				return "", err
+			}
+			if string(username) != user || string(pass) != password {
+				_, err := conn.Write([]byte{0x01, 0x01})
+				return "", errors.Join(err, fmt.Errorf("bad credentials %s:%s", username, pass))
+			}
+			if _, err := conn.Write([]byte{0x01, 0x00}); err != nil {
+				return "", err
+			}
+		}
+
+		request := make([]byte, 4)
+		if _, err := io.ReadFull(conn, request); err != nil {
+			return "", err
+		}
+		var host string
+		switch request[3] {
+		case 0x01, 0x04:
+			addr := make([]byte, net.IPv4len)
+			if request[3] == 0x04 {
+				addr = make([]byte, net.IPv6len)
+			}
+			if _, err := io.ReadFull(conn, addr); err != nil {
+				return "", err
+			}
+			ip, _ := netip.AddrFromSlice(addr)
+			host = ip.String()
+		case 0x03:
+			length := make([]byte, 1)
+			if _, err := io.ReadFull(conn, length); err != nil {
+				return "", err
+			}
+			name := make([]byte, length[0])
+			if _, err := io.ReadFull(conn, name); err != nil {
+				return "", err
+			}
+			host = string(name)
+		}
+		port := make([]byte, 2)
+		if _, err := io.ReadFull(conn, port); err != nil {
+			return "", err
+		}
+		// Reply with a bound address of 0.0.0.0:0
+		if _, err := conn.Write([]byte{0x05, code, 0x00, 0x01, 0, 0, 0, 0, 0, 0}); err != nil {
+			return "", err
+		}
+		return net.JoinHostPort(host, fmt.Sprint(binary.BigEndian.Uint16(port))), nil
+	}
+}
+
+// httpConnectServer returns an HTTP CONNECT handshake that replies with status.
+func httpConnectServer(status int) func(conn net.Conn) (string, error) {
+	return func(conn net.Conn) (string, error) {
+		request, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			return "", err
+		}
+		if request.Method != http.MethodConnect {
+			return "", fmt.Errorf("unexpected method %s", request.Method)
+		}
+		_, err = fmt.Fprintf(conn, "HTTP/1.1 %d %s\r\n\r\n", status, http.StatusText(status))
+		return request.Host, err
+	}
+}
+
+// proxiedTarget connects client through its proxy and returns the target the proxy was asked to connect to.
+func proxiedTarget(t *testing.T, client *Client, targets <-chan string) (string, error) {
+	t.Helper()
+	session, err := client.connectViaProxy(context.Background())
+	if err != nil {
+		return "", err
+	}
+	session.Conn.Close()
+	select {
+	case target := <-targets:
+		return target, nil
+	case <-time.After(time.Second):
+		t.Fatal("the proxy was never asked to connect")
+		return "", nil
+	}
+}
+
+func TestConnectViaSOCKS5Proxy(t *testing.T) {
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"192.0.2.1", "192.0.2.1:161"},
+		{"192.0.2.1:1161", "192.0.2.1:1161"},
+		{"2001:db8::1", "[2001:db8::1]:161"},
+		{"[2001:db8::1]:1161", "[2001:db8::1]:1161"},
+		{"core-sw1.example.com", "core-sw1.example.com:161"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			proxy, targets := startProxy(t, socks5Server("", "", 0x00))
+			client := &Client{Target: tt.target, Community: "public", Timeout: time.Second, Proxy: "socks5://" + proxy}
+			target, err := proxiedTarget(t, client, targets)
+			if err != nil {
+				t.Fatalf("connectViaProxy returned error: %v", err)
+			}
+			if target != tt.want {
+				t.Errorf("proxy was asked to connect to %s, want %s", target, tt.want)
+			}
+		})
+	}
+}
+
+func TestConnectViaSOCKS5ProxyWithCredentials(t *testing.T) {
+	proxy, targets := startProxy(t, socks5Server("monitor", "s3cret", 0x00))
+	client := &Client{Target: "192.0.2.1", Community: "public", Timeout: time.Second, Proxy: "socks5://monitor:s3cret@" + proxy}
+	target, err := proxiedTarget(t, client, targets)
+	if err != nil {
+		t.Fatalf("connectViaProxy returned error: %v", err)
+	}
+	if target != "192.0.2.1:161" {
+		t.Errorf("proxy was asked to connect to %s, want 192.0.2.1:161", target)
+	}
+}
+
+func TestConnectViaHTTPProxy(t *testing.T) {
+	proxy, targets := startProxy(t, httpConnectServer(http.StatusOK))
+	client := &Client{Target: "[2001:db8::1]:1161", Community: "public", Timeout: time.Second, Proxy: "http://" + proxy}
+	target, err := proxiedTarget(t, client, targets)
+	if err != nil {
+		t.Fatalf("connectViaProxy returned error: %v", err)
+	}
+	if target != "[2001:db8::1]:1161" {
+		t.Errorf("proxy was asked to connect to %s, want [2001:db8::1]:1161", target)
+	}
+}
+
+func TestConnectViaProxyRejected(t *testing.T) {
+	tests := []struct {
+		name      string
+		handshake func(conn net.Conn) (string, error)
+		proxy     string
+	}{
+		{"socks5 connection refused", socks5Server("", "", 0x05), "socks5://"},
+		{"socks5 bad credentials", socks5Server("monitor", "s3cret", 0x00), "socks5://monitor:wrong@"},
+		{"http forbidden", httpConnectServer(http.StatusForbidden), "http://"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy, _ := startProxy(t, tt.handshake)
+			client := &Client{Target: "192.0.2.1", Community: "public", Timeout: time.Second, Proxy: tt.proxy + proxy}
+			_, err := client.connectViaProxy(context.Background())
+			if !errors.Is(err, ErrConnect) {
+				t.Errorf("connectViaProxy returned %v, want an ErrConnect", err)
+			}
+		})
+	}
+}
+
+func TestConnectViaProxyInvalid(t *testing.T) {
+	for _, proxy := range []string{"socks5://127.0.0.1", "ftp://127.0.0.1:21", "socks5://127.0.0.1:99999"} {
+		client := &Client{Target: "192.0.2.1", Community: "public", Timeout: time.Second, Proxy: proxy}
+		if _, err := client.connectViaProxy(context.Background()); err == nil {
+			t.Errorf("connectViaProxy with proxy %s returned no error", proxy)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package snmp
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// defaultBackoff is the delay before the second attempt when none is configured.
+const defaultBackoff = time.Second
+
+// attempts returns the configured number of attempts, at least 1.
+func (s *Client) attempts() int {
+	if s.Attempts < 1 {
+		return 1
+	}
+	return s.Attempts
+}
+
+// backoff returns the configured initial delay between attempts, or the default when unset.
+func (s *Client) backoff() time.Duration {
+	if s.Backoff <= 0 {
+		return defaultBackoff
+	}
+	return s.Backoff
+}
+
+// isTransient reports whether err is a timeout or connection failure that may resolve on another attempt.
+func isTransient(err error) bool {
+	return errors.Is(err, ErrTimeout) || errors.Is(err, ErrConnect)
+}
+
+// withRetry calls op up to client.Attempts times while it fails with a transient error, waiting the client's
+// backoff before the second attempt and doubling it before each following one. It stops early when ctx is done.
+// The result of the last attempt is returned.
+func withRetry[T any](ctx context.Context, client *Client, op func() (T, time.Duration, error)) (T, time.Duration, error) {
+	delay := client.backoff()
+	result, latency, err := op()
+	for attempt := 1; attempt < client.attempts() && err != nil && isTransient(err); attempt++ {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, latency, err
+		case <-timer.C:
+		}
+		delay *= 2
+		result, latency, err = op()
+	}
+	return result, latency, err
+}
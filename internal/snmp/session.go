@@ -0,0 +1,334 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package snmp
+
+import (
+	"context"
+	"fmt"
+	"github.com/dmabry/gochecks/internal/clock"
+	"github.com/gosnmp/gosnmp"
+	"strings"
+	"time"
+)
+
+// Session is an open connection to an SNMP target that is reused across several operations,
+// avoiding a fresh connection for every request. Latency is still reported per operation.
+// Timeouts, refused connections and SNMPv3 authentication failures are returned as an *Error,
+// so callers can test for them with errors.Is, e.g. errors.Is(err, snmp.ErrTimeout).
+//
+// Example usage:
+//
+//	session, err := client.Open()
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer session.Close()
+//	result, latency, err := session.Get([]string{"1.3.6.1.2.1.1.1.0"})
+type Session struct {
+	client *Client
+	conn   *gosnmp.GoSNMP
+	ctx    context.Context
+	stop   func() bool
+}
+
+// PDU is a single OID and its value collected by a walk.
+type PDU struct {
+	OID   string
+	Value interface{}
+}
+
+// Table holds the rows of a walked SNMP table, keyed by the row index and then by the column number.
+// A row index is the OID suffix after the column, so it may contain several elements, e.g. "192.0.2.1".
+type Table map[string]map[int]interface{}
+
+// Open establishes a connection to the SNMP target that can be reused for several operations.
+// The session must be closed with Close when done.
+func (s *Client) Open() (*Session, error) {
+	return s.OpenContext(context.Background())
+}
+
+// OpenContext is like Open, but every operation on the session honors the deadline and
+// cancellation of ctx. If ctx is done while a request is in flight, the request returns
+// promptly with ctx.Err().
+func (s *Client) OpenContext(ctx context.Context) (*Session, error) {
+	conn, err := s.ConnectContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// gosnmp only consults the context between requests, so unblock any pending read once ctx is done.
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.Conn.SetDeadline(time.Now())
+	})
+
+	return &Session{client: s, conn: conn, ctx: ctx, stop: stop}, nil
+}
+
+// WithSession opens a session on client, calls fn with it and closes it, returning the result of fn.
+// Like the client's Get and Walk methods, the whole call is attempted up to client.Attempts times while
+// it fails with a timeout or network error, each attempt on a new session, so fn must not keep state
+// from a failed attempt.
+//
+// Example usage:
+//
+//	units, err := snmp.WithSession(&client, func(session *snmp.Session) ([]Unit, error) {
+//	    table, _, err := session.GetTable(OIDHrStorageEntry)
+//	    ...
+//	})
+func WithSession[T any](client *Client, fn func(session *Session) (T, error)) (T, error) {
+	return WithSessionContext(context.Background(), client, fn)
+}
+
+// WithSessionContext is like WithSession, but the session honors the deadline and cancellation of ctx,
+// and no further attempt is made once ctx is done.
+func WithSessionContext[T any](ctx context.Context, client *Client, fn func(session *Session) (T, error)) (T, error) {
+	result, _, err := withRetry(ctx, client, func() (T, time.Duration, error) {
+		session, err := client.OpenContext(ctx)
+		if err != nil {
+			var zero T
+			return zero, 0, err
+		}
+		defer session.Close()
+
+		result, err := fn(session)
+		return result, 0, err
+	})
+	return result, err
+}
+
+// Close closes the connection to the SNMP target.
+func (sess *Session) Close() error {
+	sess.stop()
+	return sess.conn.Conn.Close()
+}
+
+// Get retrieves SNMP values for the given OIDs.
+// OID lists longer than the client's MaxOidsPerPDU are split into several sequential GETs, whose
+// variables are merged into the first response in the order the OIDs were requested.
+// It returns the SNMP packet containing the result values, the total duration of the SNMP requests,
+// and any error encountered during the process.
+func (sess *Session) Get(oids []string) (*gosnmp.SnmpPacket, time.Duration, error) {
+	chunkSize := sess.client.maxOidsPerPDU()
+	var result *gosnmp.SnmpPacket
+	var latency time.Duration
+
+	for len(oids) > 0 || result == nil {
+		chunk := oids[:min(chunkSize, len(oids))]
+		oids = oids[len(chunk):]
+
+		start := clock.Now()
+		packet, err := sess.conn.Get(chunk)
+		if ctxErr := sess.ctx.Err(); ctxErr != nil {
+			return nil, 0, ctxErr
+		}
+		if err != nil {
+			return nil, 0, classify(err)
+		}
+		latency += clock.Since(start)
+
+		for _, variable := range packet.Variables {
+			sess.client.logValue(variable.Name, variable.Value)
+		}
+		if result == nil {
+			result = packet
+		} else {
+			result.Variables = append(result.Variables, packet.Variables...)
+		}
+	}
+
+	return result, latency, nil
+}
+
+// Walk retrieves SNMP tree for the given OID.
+// It returns a map with the OID as the key and its value as the value,
+// the duration of the SNMP request, and any error encountered during the process.
+func (sess *Session) Walk(baseOid string) (map[string]interface{}, time.Duration, error) {
+	oidValues := make(map[string]interface{})
+
+	latency, err := sess.walk(baseOid, func(pdu gosnmp.SnmpPDU) {
+		oidValues[pdu.Name] = pdu.Value
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return oidValues, latency, nil
+}
+
+// WalkOrdered retrieves SNMP tree for the given OID, returning the OIDs and values in the order
+// they were walked, the duration of the SNMP request, and any error encountered during the process.
+func (sess *Session) WalkOrdered(baseOid string) ([]PDU, time.Duration, error) {
+	var pdus []PDU
+
+	latency, err := sess.walk(baseOid, func(pdu gosnmp.SnmpPDU) {
+		pdus = append(pdus, PDU{OID: pdu.Name, Value: pdu.Value})
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return pdus, latency, nil
+}
+
+// GetTable walks the SNMP table entry given by entryOid, e.g. IF-MIB::ifEntry ".1.3.6.1.2.1.2.2.1",
+// and returns its values keyed by row index and column number, the duration of the SNMP request,
+// and any error encountered during the process.
+func (sess *Session) GetTable(entryOid string) (Table, time.Duration, error) {
+	table := make(Table)
+	var parseErr error
+
+	latency, err := sess.walk(entryOid, func(pdu gosnmp.SnmpPDU) {
+		columnNumber, index, err := ColumnIndex(entryOid, pdu.Name)
+		if err != nil {
+			parseErr = err
+			return
+		}
+		if _, ok := table[index]; !ok {
+			table[index] = make(map[int]interface{})
+		}
+		table[index][columnNumber] = pdu.Value
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if parseErr != nil {
+		return nil, 0, parseErr
+	}
+
+	return table, latency, nil
+}
+
+// walk walks the SNMP tree under baseOid with GETBULK, or GETNEXT when the client's GetNext is set, calling
+// walkFn for every PDU in the order it was received, and returns the duration of the walk.
+func (sess *Session) walk(baseOid string, walkFn func(pdu gosnmp.SnmpPDU)) (time.Duration, error) {
+	start := clock.Now()
+
+	handler := func(pdu gosnmp.SnmpPDU) error {
+		walkFn(pdu)
+		sess.client.logValue(pdu.Name, pdu.Value)
+		return sess.ctx.Err()
+	}
+	var err error
+	switch {
+	case sess.client.WalkDelay > 0:
+		err = sess.pacedWalk(baseOid, handler)
+	case sess.client.GetNext:
+		err = sess.conn.Walk(baseOid, handler)
+	default:
+		err = sess.conn.BulkWalk(baseOid, handler)
+	}
+	if ctxErr := sess.ctx.Err(); ctxErr != nil {
+		return 0, ctxErr
+	}
+	if err != nil {
+		return 0, classify(err)
+	}
+
+	return clock.Since(start), nil
+}
+
+// pacedWalk walks the SNMP tree under baseOid like walk, but sleeps for the client's WalkDelay between
+// requests, so agents on low-end devices are not overwhelmed by back-to-back requests.
+func (sess *Session) pacedWalk(baseOid string, handler func(pdu gosnmp.SnmpPDU) error) error {
+	root := "." + strings.Trim(baseOid, ".")
+	oid := root
+	for {
+		var packet *gosnmp.SnmpPacket
+		var err error
+		if sess.client.GetNext {
+			packet, err = sess.conn.GetNext([]string{oid})
+		} else {
+			packet, err = sess.conn.GetBulk([]string{oid}, 0, sess.client.maxRepetitions())
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, pdu := range packet.Variables {
+			switch pdu.Type {
+			case gosnmp.EndOfMibView, gosnmp.NoSuchObject, gosnmp.NoSuchInstance:
+				return nil
+			}
+			if !strings.HasPrefix(pdu.Name, root+".") {
+				return nil
+			}
+			if pdu.Name == oid {
+				return fmt.Errorf("OID %s is not increasing during the walk of %s", pdu.Name, baseOid)
+			}
+			if err := handler(pdu); err != nil {
+				return err
+			}
+			oid = pdu.Name
+		}
+		if len(packet.Variables) == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(sess.client.WalkDelay)
+		select {
+		case <-sess.ctx.Done():
+			timer.Stop()
+			return sess.ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// SetError is returned by Set when the agent rejects a SET request. It carries the SNMP
+// error-status and the 1-based error-index of the varbind that failed, along with its OID.
+type SetError struct {
+	Status gosnmp.SNMPError
+	Index  uint8
+	OID    string
+}
+
+// Error returns a description of the failed varbind.
+func (e *SetError) Error() string {
+	return fmt.Sprintf("SNMP set failed with %s on varbind %d (%s)", e.Status, e.Index, e.OID)
+}
+
+// Set writes the given varbinds to the SNMP target.
+// It returns the SNMP response packet, the duration of the SNMP request, and any error encountered
+// during the process. If the agent rejects the request, the error is a *SetError identifying the
+// failed varbind.
+func (sess *Session) Set(pdus []gosnmp.SnmpPDU) (*gosnmp.SnmpPacket, time.Duration, error) {
+	if len(pdus) == 0 {
+		return nil, 0, fmt.Errorf("no varbinds to set")
+	}
+
+	start := clock.Now()
+
+	result, err := sess.conn.Set(pdus)
+	if ctxErr := sess.ctx.Err(); ctxErr != nil {
+		return nil, 0, ctxErr
+	}
+	if err != nil {
+		return nil, 0, classify(err)
+	}
+
+	latency := clock.Since(start)
+
+	if result.Error != gosnmp.NoError {
+		setErr := &SetError{Status: result.Error, Index: result.ErrorIndex}
+		if index := int(result.ErrorIndex); index > 0 && index <= len(pdus) {
+			setErr.OID = pdus[index-1].Name
+		}
+		return result, latency, setErr
+	}
+
+	return result, latency, nil
+}
@@ -0,0 +1,148 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package snmp
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dmabry/gochecks/internal/snmp/snmptest"
+	"github.com/gosnmp/gosnmp"
+	"testing"
+	"time"
+)
+
+func TestGetChunks(t *testing.T) {
+	const count = 200
+	values := make(map[string]interface{}, count)
+	oids := make([]string, count)
+	for i := range oids {
+		oids[i] = fmt.Sprintf(".1.3.6.1.4.1.99999.1.%d", i+1)
+		values[oids[i]] = i
+	}
+	agent := snmptest.NewAgent(t, values)
+
+	client := Client{Target: agent.Target, Community: "public", Timeout: time.Second, Retries: -1, MaxOidsPerPDU: 10}
+	result, _, err := client.GetValue(oids)
+	if err != nil {
+		t.Fatalf("GetValue returned error: %v", err)
+	}
+
+	requests := agent.Requests()
+	if len(requests) != count/10 {
+		t.Errorf("agent received %d requests, want %d", len(requests), count/10)
+	}
+	for i, request := range requests {
+		if len(request) > 10 {
+			t.Errorf("request %d has %d OIDs, want at most 10", i, len(request))
+		}
+	}
+
+	if len(result.Variables) != count {
+		t.Fatalf("GetValue returned %d variables, want %d", len(result.Variables), count)
+	}
+	for i, variable := range result.Variables {
+		if variable.Name != oids[i] {
+			t.Errorf("variable %d is %s, want %s", i, variable.Name, oids[i])
+		}
+		if variable.Value != i {
+			t.Errorf("variable %d has value %v, want %d", i, variable.Value, i)
+		}
+	}
+}
+
+func TestGetDefaultChunkSize(t *testing.T) {
+	oids := make([]string, 25)
+	for i := range oids {
+		oids[i] = fmt.Sprintf(".1.3.6.1.4.1.99999.1.%d", i+1)
+	}
+	agent := snmptest.NewAgent(t, nil)
+
+	client := Client{Target: agent.Target, Community: "public", Timeout: time.Second, Retries: -1}
+	result, _, err := client.GetValue(oids)
+	if err != nil {
+		t.Fatalf("GetValue returned error: %v", err)
+	}
+	if len(result.Variables) != len(oids) {
+		t.Errorf("GetValue returned %d variables, want %d", len(result.Variables), len(oids))
+	}
+	var sizes []int
+	for _, request := range agent.Requests() {
+		sizes = append(sizes, len(request))
+	}
+	if fmt.Sprint(sizes) != "[10 10 5]" {
+		t.Errorf("request sizes are %v, want [10 10 5]", sizes)
+	}
+}
+
+func TestSet(t *testing.T) {
+	const oidAlias, oidAdminStatus = ".1.3.6.1.2.1.31.1.1.1.18.3", ".1.3.6.1.2.1.2.2.1.7.3"
+	agent := snmptest.NewAgent(t, map[string]interface{}{oidAlias: "uplink", oidAdminStatus: 1})
+	client := Client{Target: agent.Target, Community: "private", Timeout: time.Second, Retries: -1}
+
+	pdus := []gosnmp.SnmpPDU{
+		{Name: oidAlias, Type: gosnmp.OctetString, Value: "uplink to core"},
+		{Name: oidAdminStatus, Type: gosnmp.Integer, Value: 2},
+	}
+	if _, _, err := client.Set(pdus); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+
+	result, _, err := client.GetValue([]string{oidAlias, oidAdminStatus})
+	if err != nil {
+		t.Fatalf("GetValue returned error: %v", err)
+	}
+	if alias := ToString(result.Variables[0].Value); alias != "uplink to core" {
+		t.Errorf("ifAlias is %q after the set, want %q", alias, "uplink to core")
+	}
+	if status := result.Variables[1].Value; status != 2 {
+		t.Errorf("ifAdminStatus is %v after the set, want 2", status)
+	}
+}
+
+func TestSetRejected(t *testing.T) {
+	const oidAlias, oidReadOnly = ".1.3.6.1.2.1.31.1.1.1.18.3", ".1.3.6.1.2.1.1.3.0"
+	agent := snmptest.NewAgent(t, map[string]interface{}{oidAlias: "uplink"})
+	client := Client{Target: agent.Target, Community: "private", Timeout: time.Second, Retries: -1}
+
+	pdus := []gosnmp.SnmpPDU{
+		{Name: oidAlias, Type: gosnmp.OctetString, Value: "uplink to core"},
+		{Name: oidReadOnly, Type: gosnmp.TimeTicks, Value: uint32(0)},
+	}
+	_, _, err := client.Set(pdus)
+	var setErr *SetError
+	if !errors.As(err, &setErr) {
+		t.Fatalf("Set returned %v, want a *SetError", err)
+	}
+	if setErr.Status != gosnmp.NotWritable || setErr.Index != 2 || setErr.OID != oidReadOnly {
+		t.Errorf("Set returned %+v, want notWritable on varbind 2 (%s)", *setErr, oidReadOnly)
+	}
+
+	result, _, err := client.GetValue([]string{oidAlias})
+	if err != nil {
+		t.Fatalf("GetValue returned error: %v", err)
+	}
+	if alias := ToString(result.Variables[0].Value); alias != "uplink" {
+		t.Errorf("ifAlias is %q after a rejected set, want it unchanged", alias)
+	}
+}
+
+func TestSetEmpty(t *testing.T) {
+	client := Client{Target: "127.0.0.1", Community: "private", Timeout: time.Second, Retries: -1}
+	if _, _, err := client.Set(nil); err == nil {
+		t.Error("Set returned no error for no varbinds")
+	}
+}
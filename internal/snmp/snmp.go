@@ -17,25 +17,69 @@
 package snmp
 
 import (
+	"context"
+	"fmt"
 	"github.com/gosnmp/gosnmp"
+	"log/slog"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // timeout15 is a constant representing a timeout duration of 15 seconds.
+// defaultRetries is the number of retries used when none is configured.
+// defaultMaxRepetitions is the GETBULK max-repetitions used by walks when none is configured.
+// defaultMaxOidsPerPDU is the number of OIDs sent in a single GET when none is configured, low enough
+// for agents that only accept a handful of varbinds per PDU.
 const (
-	timeout15 = time.Duration(15) * time.Second
+	timeout15             = time.Duration(15) * time.Second
+	defaultRetries        = 1
+	defaultMaxRepetitions = 25
+	defaultMaxOidsPerPDU  = 10
 )
 
 // Client represents an SNMP client that allows connecting to a target SNMP device.
+// When LogValues is set, every OID/value collected by the client is logged at debug level to
+// stderr so it never pollutes the plugin output on stdout.
+// When Debug is set, gosnmp's traces of every request and response PDU are logged at debug level to stderr.
+// When Proxy is set, the target is reached through a SOCKS5 or HTTP CONNECT proxy over TCP.
+// A zero Timeout or Retries uses the defaults of 15 seconds and 1 retry; a negative Retries disables retries.
+// MaxRepetitions and NonRepeaters tune the GETBULK requests issued by walks; a zero MaxRepetitions uses 25.
+// Transport selects "udp" (the default) or "tcp" for agents whose responses exceed the UDP MTU.
+// MaxOidsPerPDU limits the OIDs sent in a single GET; longer lists are split into several GETs. A zero value uses 10.
+// WalkDelay paces walks for fragile agents by sleeping between the requests of a walk, and GetNext walks with
+// GETNEXT instead of GETBULK for agents that mis-implement GETBULK.
+// Attempts is the number of times a Get or Walk is tried when it fails with a timeout or network error,
+// waiting Backoff (1 second when unset) before the second attempt and doubling it each time after.
+// Unlike Retries, which resends a request within one session, every attempt opens a new session. Callers
+// running several operations on one session get the same attempts through WithSession.
 type Client struct {
-	Target    string
-	Community string
+	Target         string
+	Community      string
+	Transport      string
+	Timeout        time.Duration
+	Retries        int
+	MaxRepetitions uint32
+	NonRepeaters   int
+	MaxOidsPerPDU  int
+	WalkDelay      time.Duration
+	GetNext        bool
+	LogValues      bool
+	Debug          bool
+	Proxy          string
+	Attempts       int
+	Backoff        time.Duration
 }
 
 // Connect establishes a connection to the SNMP target using the provided parameters,
 // and returns a GoSNMP client instance along with any error encountered during connection.
 // The function sets the default SNMP port to 161 and the SNMP version to 2c.
-// The function also sets the timeout duration to 15 seconds.
+// The target may be a hostname, an IPv4 address or an IPv6 address with an optional zone,
+// optionally in brackets and followed by a port, e.g. "[fe80::1%eth0]:1161".
+// The timeout and retries come from the client, defaulting to 15 seconds and 1 retry.
+// If a proxy is configured, the connection is tunneled through it using TCP.
 // If an error occurs while connecting to the target, nil is returned along with the error.
 //
 // Example usage:
@@ -48,66 +92,269 @@ type Client struct {
 // defer snmpClient.Conn.Close()
 // ...
 func (s *Client) Connect() (*gosnmp.GoSNMP, error) {
+	return s.ConnectContext(context.Background())
+}
+
+// ConnectContext is like Connect, but the connection and every request made on it honor the
+// deadline and cancellation of ctx.
+func (s *Client) ConnectContext(ctx context.Context) (*gosnmp.GoSNMP, error) {
+	if s.Proxy != "" {
+		return s.connectViaProxy(ctx)
+	}
+
+	host, port, err := splitTarget(s.Target)
+	if err != nil {
+		return nil, err
+	}
+	transport, err := s.transport()
+	if err != nil {
+		return nil, err
+	}
+
 	snmpClient := &gosnmp.GoSNMP{
-		Target:    s.Target,
-		Port:      161,
-		Community: s.Community,
-		Version:   gosnmp.Version2c,
-		Timeout:   timeout15,
+		Target:         host,
+		Port:           port,
+		Transport:      transport,
+		Community:      s.Community,
+		Version:        gosnmp.Version2c,
+		Context:        ctx,
+		Timeout:        s.timeout(),
+		Retries:        s.retries(),
+		MaxRepetitions: s.maxRepetitions(),
+		NonRepeaters:   s.NonRepeaters,
+		MaxOids:        s.maxOidsPerPDU(),
+		Logger:         s.logger(),
 	}
 
 	if err := snmpClient.Connect(); err != nil {
-		return nil, err
+		return nil, &Error{Kind: ErrConnect, Err: err}
 	}
 
 	return snmpClient, nil
 }
 
+// splitTarget normalizes an SNMP target into a host and port suitable for gosnmp.
+// It accepts "host", "host:port", IPv6 literals such as "2001:db8::1" or "fe80::1%eth0",
+// and bracketed IPv6 literals with or without a port such as "[2001:db8::1]:161".
+// The port defaults to 161 when not given.
+func splitTarget(target string) (string, uint16, error) {
+	host := target
+	port := "161"
+	if strings.HasPrefix(target, "[") {
+		if strings.HasSuffix(target, "]") {
+			host = strings.TrimSuffix(strings.TrimPrefix(target, "["), "]")
+		} else {
+			var err error
+			if host, port, err = net.SplitHostPort(target); err != nil {
+				return "", 0, fmt.Errorf("invalid target %s: %w", target, err)
+			}
+		}
+	} else if strings.Count(target, ":") == 1 {
+		// A single colon can only separate a hostname or IPv4 address from a port.
+		host, port, _ = strings.Cut(target, ":")
+	}
+
+	if host == "" {
+		return "", 0, fmt.Errorf("invalid target %s: missing host", target)
+	}
+	if strings.Contains(host, ":") {
+		if _, err := netip.ParseAddr(host); err != nil {
+			return "", 0, fmt.Errorf("invalid IPv6 target %s: %w", target, err)
+		}
+	}
+	portNumber, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port in target %s", target)
+	}
+
+	return host, uint16(portNumber), nil
+}
+
+// transport returns the configured transport, or udp when unset.
+func (s *Client) transport() (string, error) {
+	switch s.Transport {
+	case "", "udp":
+		return "udp", nil
+	case "tcp":
+		return "tcp", nil
+	default:
+		return "", fmt.Errorf("unsupported transport '%s', expected udp or tcp", s.Transport)
+	}
+}
+
+// timeout returns the configured timeout, or the 15 second default when unset.
+func (s *Client) timeout() time.Duration {
+	if s.Timeout <= 0 {
+		return timeout15
+	}
+	return s.Timeout
+}
+
+// maxRepetitions returns the configured GETBULK max-repetitions, or the default when unset.
+func (s *Client) maxRepetitions() uint32 {
+	if s.MaxRepetitions == 0 {
+		return defaultMaxRepetitions
+	}
+	return s.MaxRepetitions
+}
+
+// maxOidsPerPDU returns the configured number of OIDs per GET, or the default when unset.
+func (s *Client) maxOidsPerPDU() int {
+	if s.MaxOidsPerPDU <= 0 {
+		return defaultMaxOidsPerPDU
+	}
+	return s.MaxOidsPerPDU
+}
+
+// retries returns the configured number of retries, or the default when unset.
+func (s *Client) retries() int {
+	if s.Retries < 0 {
+		return 0
+	}
+	if s.Retries == 0 {
+		return defaultRetries
+	}
+	return s.Retries
+}
+
+// oidPingSysUpTime is the SNMPv2-MIB sysUpTime.0 object read by Ping, which every agent implements.
+// oidSysName is the SNMPv2-MIB sysName.0 object read by SysName.
+const (
+	oidPingSysUpTime = ".1.3.6.1.2.1.1.3.0"
+	oidSysName       = ".1.3.6.1.2.1.1.5.0"
+)
+
+// Ping reads sysUpTime from the target with a single GET through the GetValue path, verifying that the
+// target is reachable and accepts the client's credentials. It returns the latency of the request.
+// An SNMPv1/v2c agent silently drops requests with a wrong community, so a rejected community
+// surfaces as a timeout, just like an unreachable target.
+//
+// Example usage:
+//
+//	latency, err := client.Ping()
+//	if err != nil {
+//	    log.Fatalf("%s is unreachable or rejected the community: %s", client.Target, err)
+//	}
+func (s *Client) Ping() (time.Duration, error) {
+	_, latency, err := s.GetValue([]string{oidPingSysUpTime})
+	return latency, err
+}
+
+// SysName returns the sysName of the target, its administratively assigned name, usually the hostname.
+// It returns an error when the request fails or the agent has no sysName.
+func (s *Client) SysName() (string, error) {
+	result, _, err := s.GetValue([]string{oidSysName})
+	if err != nil {
+		return "", err
+	}
+	if len(result.Variables) == 0 {
+		return "", fmt.Errorf("SNMP target %s returned no sysName", s.Target)
+	}
+	if err := VariableError(result.Variables[0]); err != nil {
+		return "", err
+	}
+	name := ToString(result.Variables[0].Value)
+	if name == "" {
+		return "", fmt.Errorf("SNMP target %s has an empty sysName", s.Target)
+	}
+	return name, nil
+}
+
 // GetValue retrieves SNMP values for the given OIDs using the client's connection.
 // It returns the SNMP packet containing the result values, the duration of the SNMP request,
 // and any error encountered during the process.
 func (s *Client) GetValue(oids []string) (*gosnmp.SnmpPacket, time.Duration, error) {
-	snmpClient, err := s.Connect()
-	if err != nil {
-		return nil, 0, err
-	}
-	defer snmpClient.Conn.Close()
+	return s.GetValueContext(context.Background(), oids)
+}
 
-	start := time.Now()
+// GetValueContext is like GetValue, but honors the deadline and cancellation of ctx.
+// If ctx is done before the request completes, ctx.Err() is returned.
+func (s *Client) GetValueContext(ctx context.Context, oids []string) (*gosnmp.SnmpPacket, time.Duration, error) {
+	return withRetry(ctx, s, func() (*gosnmp.SnmpPacket, time.Duration, error) {
+		session, err := s.OpenContext(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer session.Close()
 
-	result, err := snmpClient.Get(oids)
+		return session.Get(oids)
+	})
+}
+
+// Set writes the given varbinds to the SNMP target using the client's connection.
+// It returns the SNMP response packet, the duration of the SNMP request, and any error encountered
+// during the process. If the agent rejects the request, the error is a *SetError identifying the
+// failed varbind, so callers can use errors.As to inspect it.
+//
+// Example usage:
+//
+//	pdus := []gosnmp.SnmpPDU{{Name: ".1.3.6.1.2.1.2.2.1.7.3", Type: gosnmp.Integer, Value: 2}}
+//	_, _, err := client.Set(pdus)
+func (s *Client) Set(pdus []gosnmp.SnmpPDU) (*gosnmp.SnmpPacket, time.Duration, error) {
+	return s.SetContext(context.Background(), pdus)
+}
+
+// SetContext is like Set, but honors the deadline and cancellation of ctx.
+func (s *Client) SetContext(ctx context.Context, pdus []gosnmp.SnmpPDU) (*gosnmp.SnmpPacket, time.Duration, error) {
+	session, err := s.OpenContext(ctx)
 	if err != nil {
 		return nil, 0, err
 	}
+	defer session.Close()
 
-	latency := time.Since(start)
-
-	return result, latency, nil
+	return session.Set(pdus)
 }
 
 // Walk retrieves SNMP tree for the given OID using the client's connection.
 // It returns a map with the OID as the key and its value as the value,
 // the duration of the SNMP request, and any error encountered during the process.
 func (s *Client) Walk(baseOid string) (map[string]interface{}, time.Duration, error) {
-	snmpClient, err := s.Connect()
-	if err != nil {
-		return nil, 0, err
-	}
-	defer snmpClient.Conn.Close()
-
-	start := time.Now()
+	return s.WalkContext(context.Background(), baseOid)
+}
 
-	oidValues := make(map[string]interface{})
+// WalkContext is like Walk, but honors the deadline and cancellation of ctx.
+// Canceling ctx mid-walk stops the walk promptly and returns ctx.Err().
+func (s *Client) WalkContext(ctx context.Context, baseOid string) (map[string]interface{}, time.Duration, error) {
+	return withRetry(ctx, s, func() (map[string]interface{}, time.Duration, error) {
+		session, err := s.OpenContext(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer session.Close()
 
-	err = snmpClient.BulkWalk(baseOid, func(pdu gosnmp.SnmpPDU) error {
-		oidValues[pdu.Name] = pdu.Value
-		return nil
+		return session.Walk(baseOid)
 	})
-	if err != nil {
-		return nil, 0, err
-	}
+}
+
+// WalkOrdered retrieves SNMP tree for the given OID using the client's connection, like Walk.
+// It returns the OIDs and values in the order they were walked, which keeps the columns of a table
+// in row order, the duration of the SNMP request, and any error encountered during the process.
+func (s *Client) WalkOrdered(baseOid string) ([]PDU, time.Duration, error) {
+	return s.WalkOrderedContext(context.Background(), baseOid)
+}
 
-	latency := time.Since(start)
+// WalkOrderedContext is like WalkOrdered, but honors the deadline and cancellation of ctx.
+func (s *Client) WalkOrderedContext(ctx context.Context, baseOid string) ([]PDU, time.Duration, error) {
+	return withRetry(ctx, s, func() ([]PDU, time.Duration, error) {
+		session, err := s.OpenContext(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer session.Close()
 
-	return oidValues, latency, nil
+		return session.WalkOrdered(baseOid)
+	})
+}
+
+// logValue logs a collected OID and its value at debug level when LogValues is set.
+// Octet strings are quoted so binary values stay readable.
+func (s *Client) logValue(oid string, value interface{}) {
+	if !s.LogValues {
+		return
+	}
+	if val, ok := value.([]byte); ok {
+		slog.Debug("Collected OID", "oid", oid, "value", fmt.Sprintf("%q", val))
+		return
+	}
+	slog.Debug("Collected OID", "oid", oid, "type", fmt.Sprintf("%T", value), "value", value)
 }
@@ -0,0 +1,154 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package snmp
+
+import (
+	"bytes"
+	"errors"
+	"github.com/dmabry/gochecks/internal/snmp/snmptest"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// captureLog makes the default slog logger write debug records to the returned buffer until the test ends.
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+func TestLogValues(t *testing.T) {
+	const oidName, oidCount = ".1.3.6.1.2.1.1.5.0", ".1.3.6.1.4.1.99999.1.1"
+	agent := snmptest.NewAgent(t, map[string]interface{}{oidName: "core-sw1", oidCount: 42})
+
+	for _, logValues := range []bool{true, false} {
+		buf := captureLog(t)
+		client := Client{Target: agent.Target, Community: "public", Timeout: time.Second, Retries: -1, LogValues: logValues}
+		if _, _, err := client.GetValue([]string{oidName, oidCount}); err != nil {
+			t.Fatalf("GetValue returned error: %v", err)
+		}
+
+		output := buf.String()
+		logged := strings.Contains(output, "oid="+oidName) && strings.Contains(output, `core-sw1`) &&
+			strings.Contains(output, "oid="+oidCount) && strings.Contains(output, "value=42")
+		if logValues && !logged {
+			t.Errorf("collected values were not logged with LogValues set, log:\n%s", output)
+		}
+		if !logValues && output != "" {
+			t.Errorf("values were logged without LogValues set, log:\n%s", output)
+		}
+	}
+}
+
+func TestClientDefaults(t *testing.T) {
+	tests := []struct {
+		name        string
+		client      Client
+		wantTimeout time.Duration
+		wantRetries int
+	}{
+		{"unset", Client{}, 15 * time.Second, 1},
+		{"negative timeout", Client{Timeout: -time.Second}, 15 * time.Second, 1},
+		{"configured", Client{Timeout: 2 * time.Second, Retries: 3}, 2 * time.Second, 3},
+		{"retries disabled", Client{Retries: -1}, 15 * time.Second, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if timeout := tt.client.timeout(); timeout != tt.wantTimeout {
+				t.Errorf("timeout() = %s, want %s", timeout, tt.wantTimeout)
+			}
+			if retries := tt.client.retries(); retries != tt.wantRetries {
+				t.Errorf("retries() = %d, want %d", retries, tt.wantRetries)
+			}
+		})
+	}
+}
+
+func TestRetries(t *testing.T) {
+	const oidName = ".1.3.6.1.2.1.1.5.0"
+	tests := []struct {
+		name         string
+		retries      int
+		drop         int
+		wantErr      bool
+		wantRequests int
+	}{
+		{"answered after a retry", 1, 1, false, 2},
+		{"retries exhausted", 2, 3, true, 3},
+		{"retries disabled", -1, 1, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent := snmptest.NewAgent(t, map[string]interface{}{oidName: "core-sw1"})
+			agent.Drop(tt.drop)
+			client := Client{Target: agent.Target, Community: "public", Timeout: 100 * time.Millisecond, Retries: tt.retries}
+
+			_, _, err := client.GetValue([]string{oidName})
+			if tt.wantErr && !errors.Is(err, ErrTimeout) {
+				t.Errorf("GetValue returned %v, want an ErrTimeout", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("GetValue returned error: %v", err)
+			}
+			if requests := len(agent.Requests()); requests != tt.wantRequests {
+				t.Errorf("agent received %d requests, want %d", requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestSplitTarget(t *testing.T) {
+	tests := []struct {
+		target   string
+		wantHost string
+		wantPort uint16
+	}{
+		{"192.0.2.1", "192.0.2.1", 161},
+		{"192.0.2.1:1161", "192.0.2.1", 1161},
+		{"core-sw1.example.com", "core-sw1.example.com", 161},
+		{"core-sw1:1161", "core-sw1", 1161},
+		{"2001:db8::1", "2001:db8::1", 161},
+		{"fe80::1%eth0", "fe80::1%eth0", 161},
+		{"[2001:db8::1]", "2001:db8::1", 161},
+		{"[2001:db8::1]:1161", "2001:db8::1", 1161},
+		{"[fe80::1%eth0]:1161", "fe80::1%eth0", 1161},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			host, port, err := splitTarget(tt.target)
+			if err != nil {
+				t.Fatalf("splitTarget(%q) returned error: %v", tt.target, err)
+			}
+			if host != tt.wantHost || port != tt.wantPort {
+				t.Errorf("splitTarget(%q) = %s, %d, want %s, %d", tt.target, host, port, tt.wantHost, tt.wantPort)
+			}
+		})
+	}
+}
+
+func TestSplitTargetInvalid(t *testing.T) {
+	for _, target := range []string{"", ":161", "192.0.2.1:snmp", "192.0.2.1:70000", "[2001:db8::1]:", "[2001:db8::1", "2001:db8::zz"} {
+		if host, port, err := splitTarget(target); err == nil {
+			t.Errorf("splitTarget(%q) = %s, %d, want an error", target, host, port)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package snmptest provides a fake SNMPv2c agent for testing the checks against canned values without a device.
+package snmptest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gosnmp/gosnmp"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Agent is a fake SNMPv2c agent listening on a local UDP port. It answers GET requests with its values,
+// and with NoSuchObject for OIDs it has no value for. GETNEXT and GETBULK requests walk its values in OID order,
+// ending with EndOfMibView, so the agent also serves walks. SET requests replace the values of OIDs it has a value
+// for, and are rejected with notWritable otherwise. Every request is recorded.
+//
+// Example usage:
+//
+//	agent := snmptest.NewAgent(t, map[string]interface{}{".1.3.6.1.2.1.1.5.0": "core-sw1"})
+//	client := snmp.Client{Target: agent.Target, Community: "public"}
+//	name, err := client.SysName()
+type Agent struct {
+	// Target is the address of the agent, e.g. "127.0.0.1:40161".
+	Target string
+
+	conn   net.PacketConn
+	values map[string]interface{}
+
+	mu       sync.Mutex
+	requests [][]string
+	drop     int
+	omit     map[string]bool
+}
+
+// NewAgent starts an agent answering with values keyed by OID. Values are encoded by their Go type: int as an
+// Integer, uint32 as a Counter32, uint as a Gauge32, uint64 as a Counter64 and string or []byte as an OctetString.
+// The agent is stopped when the test ends.
+func NewAgent(t testing.TB, values map[string]interface{}) *Agent {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	agent := &Agent{Target: conn.LocalAddr().String(), conn: conn, values: make(map[string]interface{})}
+	for oid, value := range values {
+		agent.values["."+strings.TrimPrefix(oid, ".")] = value
+	}
+	t.Cleanup(func() { conn.Close() })
+	go agent.serve()
+	return agent
+}
+
+// Drop makes the agent silently ignore the next n requests, as an unreachable target would.
+func (agent *Agent) Drop(n int) {
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+	agent.drop = n
+}
+
+// Omit makes the agent leave the varbinds of oids out of its GET responses entirely, as some agents do
+// instead of answering NoSuchObject, so the response holds fewer varbinds than the request.
+func (agent *Agent) Omit(oids ...string) {
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+	if agent.omit == nil {
+		agent.omit = make(map[string]bool)
+	}
+	for _, oid := range oids {
+		agent.omit["."+strings.TrimPrefix(oid, ".")] = true
+	}
+}
+
+// omitted reports whether the varbind of oid is left out of GET responses.
+func (agent *Agent) omitted(oid string) bool {
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+	return agent.omit[oid]
+}
+
+// Requests returns the OIDs of every request the agent received, including dropped ones, in order.
+func (agent *Agent) Requests() [][]string {
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+	return append([][]string(nil), agent.requests...)
+}
+
+// serve answers requests until the connection is closed.
+func (agent *Agent) serve() {
+	decoder := &gosnmp.GoSNMP{Version: gosnmp.Version2c, Logger: gosnmp.NewLogger(nil)}
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := agent.conn.ReadFrom(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			continue
+		}
+		request, err := decoder.SnmpDecodePacket(buf[:n])
+		if err != nil {
+			continue
+		}
+		if !agent.record(request) {
+			continue
+		}
+		response, err := agent.respond(request)
+		if err != nil {
+			continue
+		}
+		_, _ = agent.conn.WriteTo(response, addr)
+	}
+}
+
+// record records request and reports whether it should be answered.
+func (agent *Agent) record(request *gosnmp.SnmpPacket) bool {
+	oids := make([]string, len(request.Variables))
+	for i, variable := range request.Variables {
+		oids[i] = variable.Name
+	}
+
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+	agent.requests = append(agent.requests, oids)
+	if agent.drop > 0 {
+		agent.drop--
+		return false
+	}
+	return true
+}
+
+// respond encodes the response to a GET, GETNEXT, GETBULK or SET request.
+func (agent *Agent) respond(request *gosnmp.SnmpPacket) ([]byte, error) {
+	response := &gosnmp.SnmpPacket{
+		Version:   gosnmp.Version2c,
+		Community: request.Community,
+		PDUType:   gosnmp.GetResponse,
+		RequestID: request.RequestID,
+		Logger:    gosnmp.NewLogger(nil),
+	}
+	switch request.PDUType {
+	case gosnmp.GetRequest:
+		for _, variable := range request.Variables {
+			if agent.omitted(variable.Name) {
+				continue
+			}
+			response.Variables = append(response.Variables, pdu(variable.Name, agent.values[variable.Name]))
+		}
+	case gosnmp.GetNextRequest:
+		for _, variable := range request.Variables {
+			response.Variables = append(response.Variables, agent.next(variable.Name, 1)...)
+		}
+	case gosnmp.GetBulkRequest:
+		for i, variable := range request.Variables {
+			repetitions := int(request.MaxRepetitions)
+			if i < int(request.NonRepeaters) {
+				repetitions = 1
+			}
+			response.Variables = append(response.Variables, agent.next(variable.Name, repetitions)...)
+		}
+	case gosnmp.SetRequest:
+		agent.set(request, response)
+	default:
+		return nil, fmt.Errorf("unsupported PDU type %s", request.PDUType)
+	}
+	return response.MarshalMsg()
+}
+
+// set applies a SET request and fills in its response, which echoes the varbinds of the request. Like a real
+// agent, it applies either every varbind or none, rejecting the request with notWritable and the 1-based index
+// of the first varbind whose OID the agent has no value for.
+func (agent *Agent) set(request *gosnmp.SnmpPacket, response *gosnmp.SnmpPacket) {
+	response.Variables = request.Variables
+	for i, variable := range request.Variables {
+		if _, ok := agent.values[variable.Name]; !ok {
+			response.Error = gosnmp.NotWritable
+			response.ErrorIndex = uint8(i + 1)
+			return
+		}
+	}
+	for _, variable := range request.Variables {
+		agent.values[variable.Name] = value(variable)
+	}
+}
+
+// next returns the varbinds of up to n values whose OIDs follow oid, ending with EndOfMibView when the agent has
+// fewer than n of them.
+func (agent *Agent) next(oid string, n int) []gosnmp.SnmpPDU {
+	oids := make([]string, 0, len(agent.values))
+	for candidate := range agent.values {
+		if compareOIDs(candidate, oid) > 0 {
+			oids = append(oids, candidate)
+		}
+	}
+	sort.Slice(oids, func(i, j int) bool { return compareOIDs(oids[i], oids[j]) < 0 })
+
+	var variables []gosnmp.SnmpPDU
+	for _, next := range oids {
+		if len(variables) == n {
+			return variables
+		}
+		variables = append(variables, pdu(next, agent.values[next]))
+	}
+	return append(variables, gosnmp.SnmpPDU{Name: oid, Type: gosnmp.EndOfMibView})
+}
+
+// compareOIDs compares two dotted OIDs arc by arc, returning a negative number when a sorts before b, a positive
+// number when it sorts after b, and 0 when they are equal.
+func compareOIDs(a string, b string) int {
+	arcsA := strings.Split(strings.TrimPrefix(a, "."), ".")
+	arcsB := strings.Split(strings.TrimPrefix(b, "."), ".")
+	for i := 0; i < len(arcsA) && i < len(arcsB); i++ {
+		arcA, _ := strconv.Atoi(arcsA[i])
+		arcB, _ := strconv.Atoi(arcsB[i])
+		if arcA != arcB {
+			return arcA - arcB
+		}
+	}
+	return len(arcsA) - len(arcsB)
+}
+
+// value returns the value of a decoded varbind as the Go type pdu encodes it from. Octet strings are copied,
+// since they point into the read buffer of the agent.
+func value(variable gosnmp.SnmpPDU) interface{} {
+	switch variable.Type {
+	case gosnmp.OctetString:
+		return append([]byte(nil), variable.Value.([]byte)...)
+	case gosnmp.Counter32:
+		return uint32(gosnmp.ToBigInt(variable.Value).Uint64())
+	case gosnmp.Gauge32:
+		return uint(gosnmp.ToBigInt(variable.Value).Uint64())
+	case gosnmp.Counter64:
+		return gosnmp.ToBigInt(variable.Value).Uint64()
+	default:
+		return variable.Value
+	}
+}
+
+// pdu returns the varbind of oid holding value, or NoSuchObject when value is nil.
+func pdu(oid string, value interface{}) gosnmp.SnmpPDU {
+	switch val := value.(type) {
+	case int:
+		return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.Integer, Value: val}
+	case uint32:
+		return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.Counter32, Value: val}
+	case uint:
+		return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.Gauge32, Value: val}
+	case uint64:
+		return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.Counter64, Value: val}
+	case string:
+		return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.OctetString, Value: []byte(val)}
+	case []byte:
+		return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.OctetString, Value: val}
+	default:
+		return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.NoSuchObject}
+	}
+}
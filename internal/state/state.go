@@ -0,0 +1,84 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// unsafeChars matches the characters of a key that are not safe to use in a file name.
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Path returns the path of the JSON state file for key under dir.
+// Characters of the key that are unsafe in a file name, such as the colons of an IPv6 target, are replaced.
+func Path(dir string, key string) string {
+	return filepath.Join(dir, unsafeChars.ReplaceAllString(key, "_")+".json")
+}
+
+// Load reads the state saved under key in dir into v.
+// It returns false without an error when no state has been saved yet, e.g. on the first run of a check.
+//
+// Example usage:
+//
+//	var previous Sample
+//	found, err := state.Load("/var/lib/gochecks", "check_interface_usage_192.0.2.1_3", &previous)
+func Load(dir string, key string, v interface{}) (bool, error) {
+	data, err := os.ReadFile(Path(dir, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to parse state file %s: %w", Path(dir, key), err)
+	}
+	return true, nil
+}
+
+// Save writes v as JSON under key in dir, creating dir if needed.
+// The file is replaced atomically so a concurrent Load never sees a partial write.
+func Save(dir string, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".state-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), Path(dir, key))
+}
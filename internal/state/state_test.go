@@ -0,0 +1,105 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// sample is a state value like the ones the checks save between runs.
+type sample struct {
+	Name      string    `json:"name"`
+	Counter   uint64    `json:"counter"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "gochecks")
+	saved := sample{Name: "Te1/1", Counter: 18446744073709551615, Timestamp: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	if err := Save(dir, "check_interface_usage_192.0.2.1_3", saved); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	var loaded sample
+	found, err := Load(dir, "check_interface_usage_192.0.2.1_3", &loaded)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if !found {
+		t.Fatal("Load did not find the saved state")
+	}
+	if loaded.Name != saved.Name || loaded.Counter != saved.Counter || !loaded.Timestamp.Equal(saved.Timestamp) {
+		t.Errorf("Load = %+v, want %+v", loaded, saved)
+	}
+
+	// Saving again replaces the state and leaves no temporary files behind
+	saved.Counter = 42
+	if err := Save(dir, "check_interface_usage_192.0.2.1_3", saved); err != nil {
+		t.Fatalf("second Save returned error: %v", err)
+	}
+	if _, err := Load(dir, "check_interface_usage_192.0.2.1_3", &loaded); err != nil || loaded.Counter != 42 {
+		t.Errorf("Load after the second Save = %+v, %v, want counter 42", loaded, err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("state dir holds %d files, want only the state file", len(entries))
+	}
+}
+
+func TestLoadNotFound(t *testing.T) {
+	var loaded sample
+	found, err := Load(t.TempDir(), "check_interface_usage_192.0.2.1_3", &loaded)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if found {
+		t.Error("Load found state that was never saved")
+	}
+}
+
+func TestLoadCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(Path(dir, "corrupt"), []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var loaded sample
+	if found, err := Load(dir, "corrupt", &loaded); err == nil || found {
+		t.Errorf("Load of a corrupt state file = %t, %v, want an error", found, err)
+	}
+}
+
+func TestPath(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"check_interface_usage_192.0.2.1_3", "check_interface_usage_192.0.2.1_3.json"},
+		{"check_interface_usage_[2001:db8::1]:1161_3", "check_interface_usage__2001_db8_1_1161_3.json"},
+		{"../../etc/passwd", ".._.._etc_passwd.json"},
+	}
+	for _, tt := range tests {
+		if got := Path("/var/lib/gochecks", tt.key); got != filepath.Join("/var/lib/gochecks", tt.want) {
+			t.Errorf("Path(%q) = %s, want %s", tt.key, got, filepath.Join("/var/lib/gochecks", tt.want))
+		}
+	}
+}
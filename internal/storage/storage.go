@@ -0,0 +1,136 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package storage
+
+import (
+	"fmt"
+	"github.com/dmabry/gochecks/internal/snmp"
+	"sort"
+	"strconv"
+)
+
+// Unit is a row of the HOST-RESOURCES-MIB hrStorageTable, such as a RAM pool or a mounted filesystem.
+// Size and Used are counted in AllocationUnits bytes.
+type Unit struct {
+	Index           int
+	Type            string
+	Description     string
+	AllocationUnits uint64
+	Size            uint64
+	Used            uint64
+}
+
+// SizeBytes returns the size of the storage in bytes.
+func (unit *Unit) SizeBytes() uint64 {
+	return unit.Size * unit.AllocationUnits
+}
+
+// UsedBytes returns the used space of the storage in bytes.
+func (unit *Unit) UsedBytes() uint64 {
+	return unit.Used * unit.AllocationUnits
+}
+
+// UsedPercent returns the used space in percent of the size. It returns false when the agent reports a
+// size of 0, as it does for some virtual filesystems, since there is no meaningful percentage.
+func (unit *Unit) UsedPercent() (float64, bool) {
+	if unit.Size == 0 {
+		return 0, false
+	}
+	return float64(unit.Used) * 100 / float64(unit.Size), true
+}
+
+// Collect walks the hrStorageTable of the SNMP target and returns its rows ordered by index.
+//
+// Example:
+//
+//	units, err := storage.Collect(&snmpClient)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for _, unit := range units {
+//	    if unit.Type == storage.OIDHrStorageFixedDisk {
+//	        ...
+//	    }
+//	}
+func Collect(snmpClient *snmp.Client) ([]Unit, error) {
+	return snmp.WithSession(snmpClient, collect)
+}
+
+// collect reads the hrStorageTable over session for Collect.
+func collect(session *snmp.Session) ([]Unit, error) {
+	table, _, err := session.GetTable(OIDHrStorageEntry)
+	if err != nil {
+		return nil, err
+	}
+
+	units := make([]Unit, 0, len(table))
+	for index, row := range table {
+		unitIndex, err := strconv.Atoi(index)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hrStorageIndex %s: %w", index, err)
+		}
+		unit := Unit{
+			Index:       unitIndex,
+			Type:        snmp.ToString(row[columnType]),
+			Description: snmp.ToString(row[columnDescr]),
+		}
+		for column, field := range map[int]*uint64{columnAllocationUnits: &unit.AllocationUnits, columnSize: &unit.Size, columnUsed: &unit.Used} {
+			raw, ok := row[column]
+			if !ok {
+				continue
+			}
+			value, err := snmp.ToFloat64(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid hrStorageTable column %d for index %d: %w", column, unitIndex, err)
+			}
+			// The columns are Integer32, and agents report filesystems larger than 2^31 units as negative numbers
+			if value < 0 {
+				value += 1 << 32
+			}
+			*field = uint64(value)
+		}
+		units = append(units, unit)
+	}
+	sort.Slice(units, func(i, j int) bool { return units[i].Index < units[j].Index })
+
+	return units, nil
+}
+
+// The hrStorageEntry OID and the hrStorageTypes values identifying the kind of storage.
+const (
+	OIDHrStorageEntry = ".1.3.6.1.2.1.25.2.3.1"
+
+	OIDHrStorageOther         = ".1.3.6.1.2.1.25.2.1.1"
+	OIDHrStorageRam           = ".1.3.6.1.2.1.25.2.1.2"
+	OIDHrStorageVirtualMemory = ".1.3.6.1.2.1.25.2.1.3"
+	OIDHrStorageFixedDisk     = ".1.3.6.1.2.1.25.2.1.4"
+	OIDHrStorageRemovableDisk = ".1.3.6.1.2.1.25.2.1.5"
+	OIDHrStorageFloppyDisk    = ".1.3.6.1.2.1.25.2.1.6"
+	OIDHrStorageCompactDisc   = ".1.3.6.1.2.1.25.2.1.7"
+	OIDHrStorageRamDisk       = ".1.3.6.1.2.1.25.2.1.8"
+	OIDHrStorageFlashMemory   = ".1.3.6.1.2.1.25.2.1.9"
+	OIDHrStorageNetworkDisk   = ".1.3.6.1.2.1.25.2.1.10"
+)
+
+// The hrStorageEntry columns read by Collect.
+const (
+	columnType            = 2
+	columnDescr           = 3
+	columnAllocationUnits = 4
+	columnSize            = 5
+	columnUsed            = 6
+)
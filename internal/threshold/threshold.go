@@ -0,0 +1,139 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package threshold implements the Nagios plugin threshold range format, so thresholds behave the
+// same as in other monitoring plugins.
+//
+// A range is written as [@]start:end where:
+//   - "10" means 0:10, alerting when the value is below 0 or above 10.
+//   - "10:" means 10:∞, alerting when the value is below 10.
+//   - "~:10" means -∞:10, alerting when the value is above 10.
+//   - "10:20" alerts when the value is outside 10 to 20, inclusive.
+//   - "@10:20" alerts when the value is inside 10 to 20, inclusive.
+package threshold
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Range is a parsed Nagios threshold range. Start and End are inclusive and may be infinite.
+// When Inside is set, values inside the range breach it instead of values outside.
+type Range struct {
+	Start  float64
+	End    float64
+	Inside bool
+}
+
+// Parse parses a Nagios threshold range such as "10", "10:", "~:10", "10:20" or "@10:20".
+// It returns an error if the spec is empty, a bound is not a number, or the start is greater than the end.
+//
+// Example:
+//
+//	warn, err := threshold.Parse("~:80")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	if warn.Breaches(85) {
+//	    ...
+//	}
+func Parse(spec string) (Range, error) {
+	r := Range{End: math.Inf(1)}
+	value := spec
+	if strings.HasPrefix(value, "@") {
+		r.Inside = true
+		value = value[1:]
+	}
+	if value == "" {
+		return r, fmt.Errorf("invalid threshold range '%s': empty range", spec)
+	}
+	startSpec, endSpec, found := strings.Cut(value, ":")
+	if !found {
+		startSpec, endSpec = "0", value
+	}
+	switch startSpec {
+	case "~":
+		r.Start = math.Inf(-1)
+	case "":
+		r.Start = 0
+	default:
+		start, err := strconv.ParseFloat(startSpec, 64)
+		if err != nil {
+			return r, fmt.Errorf("invalid threshold range '%s': %w", spec, err)
+		}
+		r.Start = start
+	}
+	if endSpec != "" {
+		end, err := strconv.ParseFloat(endSpec, 64)
+		if err != nil {
+			return r, fmt.Errorf("invalid threshold range '%s': %w", spec, err)
+		}
+		r.End = end
+	}
+	if r.Start > r.End {
+		return r, fmt.Errorf("invalid threshold range '%s': start is greater than end", spec)
+	}
+	return r, nil
+}
+
+// ParseOptional is like Parse, but returns nil for an empty spec, so an unset threshold flag disables that level.
+func ParseOptional(spec string) (*Range, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	r, err := Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Breaches reports whether value should raise an alert for the range.
+func (r Range) Breaches(value float64) bool {
+	outside := value < r.Start || value > r.End
+	if r.Inside {
+		return !outside
+	}
+	return outside
+}
+
+// Bound returns the value reported as the warn or crit level in performance data: the end of the range,
+// or its start when the range is unbounded above.
+func (r Range) Bound() float64 {
+	if math.IsInf(r.End, 1) {
+		return r.Start
+	}
+	return r.End
+}
+
+// String formats the range in the Nagios threshold range format.
+func (r Range) String() string {
+	var spec strings.Builder
+	if r.Inside {
+		spec.WriteString("@")
+	}
+	if math.IsInf(r.Start, -1) {
+		spec.WriteString("~:")
+	} else if r.Start != 0 || math.IsInf(r.End, 1) {
+		spec.WriteString(strconv.FormatFloat(r.Start, 'g', -1, 64) + ":")
+	}
+	if !math.IsInf(r.End, 1) {
+		spec.WriteString(strconv.FormatFloat(r.End, 'g', -1, 64))
+	}
+	return spec.String()
+}
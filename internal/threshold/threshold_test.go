@@ -0,0 +1,145 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package threshold
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec   string
+		want   Range
+		string string
+	}{
+		{spec: "10", want: Range{Start: 0, End: 10}, string: "10"},
+		{spec: "10:", want: Range{Start: 10, End: math.Inf(1)}, string: "10:"},
+		{spec: "~:10", want: Range{Start: math.Inf(-1), End: 10}, string: "~:10"},
+		{spec: ":10", want: Range{Start: 0, End: 10}, string: "10"},
+		{spec: "10:20", want: Range{Start: 10, End: 20}, string: "10:20"},
+		{spec: "@10:20", want: Range{Start: 10, End: 20, Inside: true}, string: "@10:20"},
+		{spec: "-5:-1", want: Range{Start: -5, End: -1}, string: "-5:-1"},
+		{spec: "1.5:2.5", want: Range{Start: 1.5, End: 2.5}, string: "1.5:2.5"},
+		{spec: "~:", want: Range{Start: math.Inf(-1), End: math.Inf(1)}, string: "~:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %v", tt.spec, err)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.spec, got, tt.want)
+			}
+			if s := got.String(); s != tt.string {
+				t.Errorf("Parse(%q).String() = %q, want %q", tt.spec, s, tt.string)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, spec := range []string{"", "@", "abc", "10:abc", "abc:10", "20:10", "@20:10", "1:2:3"} {
+		t.Run(spec, func(t *testing.T) {
+			if r, err := Parse(spec); err == nil {
+				t.Errorf("Parse(%q) = %+v, want error", spec, r)
+			}
+		})
+	}
+}
+
+func TestParseOptional(t *testing.T) {
+	r, err := ParseOptional("")
+	if err != nil || r != nil {
+		t.Errorf("ParseOptional(\"\") = %v, %v, want nil, nil", r, err)
+	}
+	r, err = ParseOptional("10:20")
+	if err != nil || r == nil || *r != (Range{Start: 10, End: 20}) {
+		t.Errorf("ParseOptional(\"10:20\") = %v, %v, want 10:20", r, err)
+	}
+	if _, err = ParseOptional("bogus"); err == nil {
+		t.Error("ParseOptional(\"bogus\") returned no error")
+	}
+}
+
+func TestBreaches(t *testing.T) {
+	tests := []struct {
+		spec  string
+		value float64
+		want  bool
+	}{
+		// Plain end: alert outside 0..10.
+		{"10", -1, true},
+		{"10", 0, false},
+		{"10", 10, false},
+		{"10", 10.1, true},
+		// Open end: alert below start only.
+		{"10:", 9, true},
+		{"10:", 10, false},
+		{"10:", 1e12, false},
+		// Open start: alert above end only.
+		{"~:10", -1e12, false},
+		{"~:10", 10, false},
+		{"~:10", 11, true},
+		// Outside range.
+		{"10:20", 9, true},
+		{"10:20", 10, false},
+		{"10:20", 15, false},
+		{"10:20", 20, false},
+		{"10:20", 21, true},
+		// Inside range.
+		{"@10:20", 9, false},
+		{"@10:20", 10, true},
+		{"@10:20", 15, true},
+		{"@10:20", 20, true},
+		{"@10:20", 21, false},
+		{"@~:10", -5, true},
+		{"@10:", 5, false},
+		{"@10:", 50, true},
+	}
+	for _, tt := range tests {
+		r, err := Parse(tt.spec)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned error: %v", tt.spec, err)
+		}
+		if got := r.Breaches(tt.value); got != tt.want {
+			t.Errorf("Parse(%q).Breaches(%v) = %v, want %v", tt.spec, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestBound(t *testing.T) {
+	tests := []struct {
+		spec string
+		want float64
+	}{
+		{"10", 10},
+		{"10:20", 20},
+		{"10:", 10},
+		{"~:10", 10},
+	}
+	for _, tt := range tests {
+		r, err := Parse(tt.spec)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned error: %v", tt.spec, err)
+		}
+		if got := r.Bound(); got != tt.want {
+			t.Errorf("Parse(%q).Bound() = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
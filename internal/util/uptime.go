@@ -0,0 +1,60 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package util holds small formatting helpers shared by the check commands.
+package util
+
+import (
+	"fmt"
+	"math"
+)
+
+// FormatUptime renders an uptime given in seconds as a short human readable duration. Uptimes of a day
+// or more show days, hours and minutes, e.g. "12d 4h 33m", and shorter ones show their two most
+// significant units, so seconds only appear under an hour, e.g. "4h 33m" or "33m 12s".
+// Uptimes under a minute are shown in whole seconds, so a sub-second uptime is "0s".
+// Negative, NaN and infinite values are treated as 0.
+//
+// Parameters:
+//   - seconds: The uptime in seconds, e.g. sysUpTime divided by 100.
+//
+// Returns:
+//   - The formatted uptime.
+//
+// Example:
+//
+//	util.FormatUptime(1052000) // "12d 4h 13m"
+func FormatUptime(seconds float64) string {
+	if math.IsNaN(seconds) || math.IsInf(seconds, 0) || seconds < 0 {
+		seconds = 0
+	}
+	total := uint64(seconds)
+	days := total / 86400
+	hours := total % 86400 / 3600
+	minutes := total % 3600 / 60
+	secs := total % 60
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh %dm", days, hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	case minutes > 0:
+		return fmt.Sprintf("%dm %ds", minutes, secs)
+	default:
+		return fmt.Sprintf("%ds", secs)
+	}
+}
@@ -0,0 +1,47 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package util
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFormatUptime(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		want    string
+	}{
+		{0, "0s"},
+		{0.4, "0s"},
+		{59, "59s"},
+		{61, "1m 1s"},
+		{3599, "59m 59s"},
+		{3600, "1h 0m"},
+		{16392, "4h 33m"},
+		{86400, "1d 0h 0m"},
+		{1052000, "12d 4h 13m"},
+		{-1, "0s"},
+		{math.NaN(), "0s"},
+		{math.Inf(1), "0s"},
+	}
+	for _, tt := range tests {
+		if got := FormatUptime(tt.seconds); got != tt.want {
+			t.Errorf("FormatUptime(%v) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+/*
+   Copyright 2024 David Mabry
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package version reports the build of the running binary, so the version deployed on each host can be
+// identified when coordinating rollouts.
+//
+// The values are injected at build time, e.g.
+//
+//	go build -ldflags "-X github.com/dmabry/gochecks/internal/version.Version=v1.2.0 \
+//	    -X github.com/dmabry/gochecks/internal/version.Commit=$(git rev-parse --short HEAD) \
+//	    -X github.com/dmabry/gochecks/internal/version.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/check_snmp
+//
+// Values that are not injected fall back to the build information embedded by the Go toolchain.
+package version
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+)
+
+// Version, Commit and Date describe the build. They are set with -ldflags -X.
+var (
+	Version = ""
+	Commit  = ""
+	Date    = ""
+)
+
+// Info returns the version, git commit and build date of the binary, filling values that were not injected
+// from the module and VCS information embedded by the Go toolchain, or "unknown" when that is missing too.
+func Info() (version string, commit string, date string) {
+	version, commit, date = Version, Commit, Date
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if version == "" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			version = info.Main.Version
+		}
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && commit == "":
+				commit = setting.Value
+			case setting.Key == "vcs.time" && date == "":
+				date = setting.Value
+			}
+		}
+	}
+	if version == "" {
+		version = "unknown"
+	}
+	if commit == "" {
+		commit = "unknown"
+	}
+	if date == "" {
+		date = "unknown"
+	}
+	return version, commit, date
+}
+
+// Print writes the name of the binary with its version, git commit and build date to w, e.g.
+// "check_snmp v1.2.0 (commit 1a2b3c4, built 2024-06-01T12:00:00Z)".
+func Print(w io.Writer) {
+	version, commit, date := Info()
+	fmt.Fprintf(w, "%s %s (commit %s, built %s)\n", filepath.Base(os.Args[0]), version, commit, date)
+}
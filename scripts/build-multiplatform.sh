@@ -7,7 +7,11 @@
 
 oses=(windows darwin linux)
 archs=(amd64 arm64)
-cmds=(check_interface_usage check_interfaces check_sysdescr)
+commit=$(git rev-parse --short HEAD 2>/dev/null || echo unknown)
+date=$(date -u +%Y-%m-%dT%H:%M:%SZ)
+pkg=github.com/dmabry/gochecks/internal/version
+ldflags="-s -w -X ${pkg}.Version=${tag} -X ${pkg}.Commit=${commit} -X ${pkg}.Date=${date}"
+cmds=(check_interface_usage check_interfaces check_sysdescr check_oid_compare check_snmp check_uptime check_cpu check_memory check_disk check_temperature check_load check_process check_env snmpwalk_csv gochecks_exporter check_snmp_latency check_interface check_ifcount)
 
 for os in "${oses[@]}"
 do
@@ -16,7 +20,7 @@ do
     for cmd in "${cmds[@]}"
     do
 	    echo "Building binary ${cmd}_${os}_${arch}_${tag}"
-      env GOOS="${os}" GOARCH="${arch}" go build -ldflags '-s -w' -a -o ./bin/"${cmd}"_"${os}"_"${arch}"_"${tag}" ./cmd/"${cmd}"
+      env GOOS="${os}" GOARCH="${arch}" go build -ldflags "${ldflags}" -a -o ./bin/"${cmd}"_"${os}"_"${arch}"_"${tag}" ./cmd/"${cmd}"
       # Drop semver to imply latest release
       cp ./bin/"${cmd}"_"${os}"_"${arch}"_"${tag}" ./bin/"${cmd}"_"${os}"_"${arch}"
     done